@@ -0,0 +1,66 @@
+// +build ignore
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+func main() {
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+
+	var (
+		inLocation = false
+		counter    = 0
+	)
+
+	PrintMemUsage()
+
+	err = xml.Parse(file, &xml.HandlerFuncs{
+		OnStartElement: func(e *xml.StartElement) error {
+			inLocation = e.NameUnsafe() == "location"
+			return nil
+		},
+		OnEndElement: func(e *xml.EndElement) error {
+			inLocation = false
+			return nil
+		},
+		OnText: func(e *xml.TextElement) error {
+			if inLocation && strings.Contains(e.String(), "Africa") {
+				counter++
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	runtime.GC()
+	PrintMemUsage()
+
+	fmt.Println("counter =", counter)
+}
+
+func PrintMemUsage() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	// For info on each, see: https://golang.org/pkg/runtime/#MemStats
+	fmt.Printf("Alloc = %v MiB", bToMb(m.Alloc))
+	fmt.Printf("\tTotalAlloc = %v MiB", bToMb(m.TotalAlloc))
+	fmt.Printf("\tSys = %v MiB", bToMb(m.Sys))
+	fmt.Printf("\tNumGC = %v\n", m.NumGC)
+}
+
+func bToMb(b uint64) uint64 {
+	return b / 1024 / 1024
+}
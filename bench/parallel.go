@@ -0,0 +1,83 @@
+// +build ignore
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+// usage: go run bench/parallel.go <file> <split element>
+func main() {
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	split := "location"
+	if len(os.Args) > 2 {
+		split = os.Args[2]
+	}
+
+	PrintMemUsage()
+
+	count := 0
+
+	p := xml.NewParallelReaderAt(file, st.Size(), split)
+	err = p.Run(func(c *xml.Chunk) (interface{}, error) {
+		var (
+			readNext = false
+			count    = 0
+		)
+
+		r := c.Reader
+		for r.Next() {
+			switch e := r.Element().(type) {
+			case *xml.StartElement:
+				readNext = e.NameUnsafe() == "location"
+			case *xml.TextElement:
+				if readNext && strings.Contains(e.String(), "Africa") {
+					count++
+					readNext = false
+				}
+			}
+		}
+
+		return count, nil
+	}, func(index int, result interface{}) error {
+		count += result.(int)
+		return nil
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	runtime.GC()
+	PrintMemUsage()
+
+	fmt.Println("counter =", count)
+}
+
+func PrintMemUsage() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	// For info on each, see: https://golang.org/pkg/runtime/#MemStats
+	fmt.Printf("Alloc = %v MiB", bToMb(m.Alloc))
+	fmt.Printf("\tTotalAlloc = %v MiB", bToMb(m.TotalAlloc))
+	fmt.Printf("\tSys = %v MiB", bToMb(m.Sys))
+	fmt.Printf("\tNumGC = %v\n", m.NumGC)
+}
+
+func bToMb(b uint64) uint64 {
+	return b / 1024 / 1024
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+const extractUsage = `usage: quickxml extract --path <path> [flags] [file ...]
+
+Prints the elements matching path, one per line. The files are read
+in order, or the standard input if there are none.
+
+A path is a list of element names separated by / starting from
+the root element, like feed/entry. The * name matches any element.
+
+The fields are a comma separated list of:
+  @name        the attribute name of the element.
+  .            the text of the element.
+  child        the text of the first descendant at the relative path child,
+               joining the text nodes found around its children.
+  child/@name  the attribute name of the first descendant at child.
+
+flags:
+`
+
+// field is a column of the records.
+type field struct {
+	name string
+	path []string // relative to the matched element.
+	attr string   // empty for the text.
+}
+
+func parseFields(s string) []field {
+	var fields []field
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		f := field{name: name}
+		path := name
+		if i := strings.LastIndexByte(path, '@'); i >= 0 {
+			f.attr = path[i+1:]
+			path = strings.TrimSuffix(path[:i], "/")
+		}
+		if path != "" && path != "." {
+			f.path = strings.Split(path, "/")
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// extractor writes the records of the elements matching a path.
+type extractor struct {
+	path   []string
+	fields []field
+	format string
+	w      *bufio.Writer
+	enc    *json.Encoder // encodes the JSON values into jbuf.
+	jbuf   bytes.Buffer
+
+	stack []string
+	depth int // depth of the matched element, or -1.
+
+	// record of the matched element
+	values []string
+	set    []bool
+	done   []bool              // the element of the field has been closed.
+	keys   []string            // keys of the default jsonl record in order.
+	texts  map[string][]string // values of the default jsonl record.
+	open   bool                // the last value of texts belongs to the open child.
+	buf    []byte
+}
+
+func newExtractor(w io.Writer, path, format, fields string) (*extractor, error) {
+	switch format {
+	case "jsonl", "xml":
+	case "tsv":
+		if fields == "" {
+			return nil, fmt.Errorf("the tsv format needs --fields")
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+	if path = strings.Trim(path, "/"); path == "" {
+		return nil, fmt.Errorf("missing --path")
+	}
+
+	ex := &extractor{
+		path:   strings.Split(path, "/"),
+		fields: parseFields(fields),
+		format: format,
+		w:      bufio.NewWriter(w),
+		depth:  -1,
+		texts:  make(map[string][]string),
+	}
+	ex.enc = json.NewEncoder(&ex.jbuf)
+	ex.enc.SetEscapeHTML(false)
+	ex.values = make([]string, len(ex.fields))
+	ex.set = make([]bool, len(ex.fields))
+	ex.done = make([]bool, len(ex.fields))
+	return ex, nil
+}
+
+func (ex *extractor) header() error {
+	for i, f := range ex.fields {
+		if i > 0 {
+			ex.w.WriteByte('\t')
+		}
+		ex.w.WriteString(escapeTSV(f.name))
+	}
+	return ex.w.WriteByte('\n')
+}
+
+// run extracts the records of the document read by r.
+func (ex *extractor) run(r *xml.Reader) error {
+	ex.stack = ex.stack[:0]
+	ex.depth = -1
+
+	for r.Next() {
+		switch e := r.Element().(type) {
+		case *xml.StartElement:
+			ex.start(e)
+			if e.HasEnd() {
+				ex.end(nil)
+			}
+		case *xml.EndElement:
+			ex.end(e)
+		case *xml.TextElement:
+			ex.text(e)
+		}
+	}
+	// the records already extracted are written even if the reader fails
+	err := ex.w.Flush()
+	if rerr := r.Error(); rerr != io.EOF {
+		err = rerr
+	}
+	return err
+}
+
+func matchPath(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (ex *extractor) start(e *xml.StartElement) {
+	ex.stack = append(ex.stack, e.Name())
+
+	if ex.depth < 0 {
+		if !matchPath(ex.path, ex.stack) {
+			return
+		}
+		ex.depth = len(ex.stack)
+		for i := range ex.set {
+			ex.values[i], ex.set[i], ex.done[i] = "", false, false
+		}
+		ex.keys = ex.keys[:0]
+		for k := range ex.texts {
+			delete(ex.texts, k)
+		}
+		ex.buf = ex.buf[:0]
+
+		if len(ex.fields) == 0 && ex.format == "jsonl" {
+			e.Attrs().Range(func(kv *xml.KV) {
+				ex.add("@"+kv.Key(), ex.unescape(kv.ValueBytes()))
+			})
+		}
+	}
+
+	rel := ex.stack[ex.depth:]
+	if len(rel) == 1 {
+		ex.open = false
+	}
+	for i, f := range ex.fields {
+		if f.attr != "" && !ex.set[i] && matchPath(f.path, rel) {
+			if kv := e.Attrs().Get(f.attr); kv != nil {
+				ex.values[i], ex.set[i] = ex.unescape(kv.ValueBytes()), true
+			}
+		}
+	}
+
+	if ex.format == "xml" {
+		ex.buf = append(ex.buf, e.String()...)
+	}
+}
+
+func (ex *extractor) text(e *xml.TextElement) {
+	if ex.depth < 0 {
+		return
+	}
+
+	rel := ex.stack[ex.depth:]
+	text := ex.unescape([]byte(e.String()))
+	for i, f := range ex.fields {
+		if f.attr == "" && !ex.done[i] && matchPath(f.path, rel) {
+			ex.values[i] += text
+			ex.set[i] = true
+		}
+	}
+
+	switch {
+	case ex.format == "xml":
+		ex.buf = append(ex.buf, strings.ReplaceAll(e.String(), "\n", "&#10;")...)
+	case len(ex.fields) > 0:
+	case len(rel) == 0:
+		ex.add("#text", text)
+	case len(rel) == 1 && ex.open: // mixed content
+		vs := ex.texts[rel[0]]
+		vs[len(vs)-1] += text
+	case len(rel) == 1:
+		ex.add(rel[0], text)
+		ex.open = true
+	}
+}
+
+func (ex *extractor) end(e *xml.EndElement) {
+	if len(ex.stack) == 0 {
+		return
+	}
+
+	if ex.depth >= 0 {
+		// the text fields take the texts of the first element matched
+		rel := ex.stack[ex.depth:]
+		for i, f := range ex.fields {
+			if f.attr == "" && ex.set[i] && matchPath(f.path, rel) {
+				ex.done[i] = true
+			}
+		}
+		if e != nil && ex.format == "xml" {
+			ex.buf = append(ex.buf, e.String()...)
+		}
+		if len(ex.stack) == ex.depth {
+			ex.write()
+			ex.depth = -1
+		}
+	}
+	ex.stack = ex.stack[:len(ex.stack)-1]
+}
+
+func (ex *extractor) unescape(b []byte) string {
+	return string(xml.Unescape(nil, b))
+}
+
+// add adds a value to the default jsonl record.
+func (ex *extractor) add(key, value string) {
+	if _, ok := ex.texts[key]; !ok {
+		ex.keys = append(ex.keys, key)
+	}
+	ex.texts[key] = append(ex.texts[key], value)
+}
+
+// write writes the record of the element matched.
+func (ex *extractor) write() {
+	switch ex.format {
+	case "xml":
+		ex.w.Write(ex.buf)
+	case "tsv":
+		for i, v := range ex.values {
+			if i > 0 {
+				ex.w.WriteByte('\t')
+			}
+			ex.w.WriteString(escapeTSV(v))
+		}
+	case "jsonl":
+		ex.w.WriteByte('{')
+		if len(ex.fields) > 0 {
+			for i, f := range ex.fields {
+				if i > 0 {
+					ex.w.WriteByte(',')
+				}
+				ex.writeJSON(f.name)
+				ex.w.WriteByte(':')
+				if ex.set[i] {
+					ex.writeJSON(ex.values[i])
+				} else {
+					ex.w.WriteString("null")
+				}
+			}
+		} else {
+			for i, k := range ex.keys {
+				if i > 0 {
+					ex.w.WriteByte(',')
+				}
+				ex.writeJSON(k)
+				ex.w.WriteByte(':')
+				if vs := ex.texts[k]; len(vs) == 1 {
+					ex.writeJSON(vs[0])
+				} else {
+					ex.writeJSON(vs)
+				}
+			}
+		}
+		ex.w.WriteByte('}')
+	}
+	ex.w.WriteByte('\n')
+}
+
+// writeJSON writes v as JSON without escaping the HTML characters.
+func (ex *extractor) writeJSON(v interface{}) {
+	ex.jbuf.Reset()
+	ex.enc.Encode(v)
+	ex.w.Write(bytes.TrimSuffix(ex.jbuf.Bytes(), []byte{'\n'}))
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func escapeTSV(s string) string {
+	return tsvEscaper.Replace(s)
+}
+
+func runExtract(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), extractUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		path   = fs.String("path", "", "path of the elements to extract, like feed/entry")
+		format = fs.String("format", "jsonl", "output format: jsonl, xml or tsv")
+		fields = fs.String("fields", "", "comma separated list of fields (all the attributes and child texts in jsonl by default)")
+		header = fs.Bool("header", false, "print the field names as the first line in tsv")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ex, err := newExtractor(os.Stdout, *path, *format, *fields)
+	if err != nil {
+		return err
+	}
+	if *header && ex.format == "tsv" {
+		ex.header()
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		if err = extractFile(ex, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(ex *extractor, name string) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if err := ex.run(xml.NewReader(r)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+const feed = `<?xml version="1.0"?>
+<feed>
+	<entry id="1" lang="en">
+		<title>First &amp; best</title>
+		<author name="ann"><email>ann@example.com</email></author>
+		<tag>a</tag><tag>b</tag>
+	</entry>
+	<entry id="2"><title>Second
+line</title></entry>
+	<other><entry id="3"/></other>
+</feed>`
+
+func extract(t *testing.T, path, format, fields string) string {
+	var b bytes.Buffer
+	ex, err := newExtractor(&b, path, format, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ex.run(xml.NewReader(strings.NewReader(feed))); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestExtract(t *testing.T) {
+	for _, tc := range []struct {
+		path, format, fields string
+		expected             string
+	}{
+		{
+			"feed/entry", "jsonl", "",
+			`{"@id":"1","@lang":"en","title":"First & best","tag":["a","b"]}` + "\n" +
+				`{"@id":"2","title":"Second\nline"}` + "\n",
+		},
+		{
+			"feed/entry", "tsv", "@id,@lang,title,author/@name,author/email",
+			"1\ten\tFirst & best\tann\tann@example.com\n" +
+				"2\t\tSecond\\nline\t\t\n",
+		},
+		{
+			"feed/entry", "jsonl", "@id,@lang",
+			`{"@id":"1","@lang":"en"}` + "\n" + `{"@id":"2","@lang":null}` + "\n",
+		},
+		{
+			"*/*/entry", "xml", "",
+			`<entry id="3"/>` + "\n",
+		},
+		{
+			"feed/entry", "xml", "",
+			`<entry id="1" lang="en"><title>First &amp; best</title><author name="ann"><email>ann@example.com</email></author><tag>a</tag><tag>b</tag></entry>` + "\n" +
+				`<entry id="2"><title>Second&#10;line</title></entry>` + "\n",
+		},
+	} {
+		if got := extract(t, tc.path, tc.format, tc.fields); got != tc.expected {
+			t.Fatalf("%s %s %s:\n%s\nexpected:\n%s", tc.path, tc.format, tc.fields, got, tc.expected)
+		}
+	}
+}
+
+func TestExtractMixedContent(t *testing.T) {
+	const doc = `<r><e><c>a<b>x</b>c</c><c>d</c></e></r>`
+
+	for _, tc := range []struct {
+		fields   string
+		expected string
+	}{
+		{"c", `{"c":"ac"}` + "\n"},
+		{"", `{"c":["ac","d"]}` + "\n"},
+	} {
+		var b bytes.Buffer
+		ex, err := newExtractor(&b, "r/e", "jsonl", tc.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ex.run(xml.NewReader(strings.NewReader(doc))); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tc.expected {
+			t.Fatalf("fields %q: unexpected output %q. Expected %q", tc.fields, b.String(), tc.expected)
+		}
+	}
+}
+
+func TestExtractReadError(t *testing.T) {
+	errRead := errors.New("read error")
+	r := io.MultiReader(strings.NewReader(`<feed><entry id="1"/><entry id="2"/>`), iotest.ErrReader(errRead))
+
+	var b bytes.Buffer
+	ex, err := newExtractor(&b, "feed/entry", "jsonl", "@id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ex.run(xml.NewReader(r)); err != errRead {
+		t.Fatalf("expected the read error. Got %v", err)
+	}
+	if expected := `{"@id":"1"}` + "\n" + `{"@id":"2"}` + "\n"; b.String() != expected {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	var b bytes.Buffer
+	if _, err := newExtractor(&b, "feed", "csv", ""); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+	if _, err := newExtractor(&b, "feed", "tsv", ""); err == nil {
+		t.Fatal("expected missing fields error")
+	}
+	if _, err := newExtractor(&b, "", "jsonl", ""); err == nil {
+		t.Fatal("expected missing path error")
+	}
+}
@@ -0,0 +1,41 @@
+// Command quickxml processes XML files from the shell.
+//
+// Usage:
+//
+//	quickxml extract --path feed/entry [--format jsonl|xml|tsv] [--fields @id,title] [file ...]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage: quickxml <command> [arguments]
+
+commands:
+  extract   print the elements matching a path, one per line
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "extract":
+		err = runExtract(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "quickxml: unknown command %s\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "quickxml:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,62 @@
+package xml
+
+import (
+	"context"
+	"io"
+)
+
+// Progress reports how far a Reader has got in the input.
+type Progress struct {
+	// Bytes is the number of bytes consumed from the input.
+	Bytes int64
+	// Elements is the number of elements read.
+	Elements int64
+	// Done is true in the last report, once the reader has stopped.
+	Done bool
+}
+
+// NewReaderContext returns a Reader that stops reading once ctx is done.
+//
+// See Reader.SetContext.
+func NewReaderContext(ctx context.Context, r io.Reader) *Reader {
+	rd := NewReader(r)
+	rd.SetContext(ctx)
+	return rd
+}
+
+// SetContext makes the reader stop once ctx is done. Next then returns false
+// and Error returns ctx.Err().
+//
+// The context is checked on every call to Next and every time the reader
+// needs more input, but a Read blocked in the underlying reader is not interrupted.
+func (r *Reader) SetContext(ctx context.Context) {
+	r.r.ctx = ctx
+}
+
+// SetProgress sets fn to be called every time the reader
+// consumes at least interval bytes since the last call,
+// and once more when the reader stops.
+//
+// fn is called from Next, so it should return quickly.
+func (r *Reader) SetProgress(interval int64, fn func(Progress)) {
+	r.progress = fn
+	r.interval = interval
+	r.reported = r.r.n
+}
+
+// report calls the progress function if needed.
+func (r *Reader) report(ok bool) {
+	if !ok {
+		if r.progress != nil && !r.done {
+			r.progress(Progress{Bytes: r.r.n, Elements: r.elements, Done: true})
+			r.done = true
+		}
+		return
+	}
+
+	r.elements++
+	if r.progress != nil && r.r.n-r.reported >= r.interval {
+		r.reported = r.r.n
+		r.progress(Progress{Bytes: r.r.n, Elements: r.elements})
+	}
+}
@@ -0,0 +1,101 @@
+package xml
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderContext(t *testing.T) {
+	data := makeSheet(10000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewReaderContext(ctx, bytes.NewReader(data))
+
+	n := 0
+	for r.Next() {
+		if n++; n == 10 {
+			cancel()
+		}
+	}
+	if err := r.Error(); err != context.Canceled {
+		t.Fatalf("expected context.Canceled. Got %v", err)
+	}
+	if n != 10 {
+		t.Fatalf("expected to stop after 10 elements. Got %d", n)
+	}
+}
+
+func TestReaderContextBuffered(t *testing.T) {
+	data := makeSheet(100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewReaderSize(bytes.NewReader(data), len(data))
+	r.SetContext(ctx)
+
+	if !r.Next() {
+		t.Fatal(r.Error())
+	}
+	cancel()
+	if r.Next() {
+		t.Fatalf("unexpected element %s after cancel", r.Element())
+	}
+	if err := r.Error(); err != context.Canceled {
+		t.Fatalf("expected context.Canceled. Got %v", err)
+	}
+}
+
+func TestReaderProgress(t *testing.T) {
+	data := makeSheet(1000)
+
+	var reports []Progress
+	r := NewReader(bytes.NewReader(data))
+	r.SetProgress(4096, func(p Progress) {
+		reports = append(reports, p)
+	})
+
+	elements := int64(0)
+	for r.Next() {
+		elements++
+	}
+	if err := r.Error(); err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if len(reports) < int(len(data)/4096) {
+		t.Fatalf("expected at least %d reports. Got %d", len(data)/4096, len(reports))
+	}
+	for i := 1; i < len(reports)-1; i++ {
+		if reports[i].Bytes-reports[i-1].Bytes < 4096 || reports[i].Done {
+			t.Fatalf("unexpected report %d: %+v", i, reports[i])
+		}
+	}
+
+	last := reports[len(reports)-1]
+	if !last.Done || last.Bytes != int64(len(data)) || last.Elements != elements {
+		t.Fatalf("unexpected last report %+v. Expected %d bytes and %d elements", last, len(data), elements)
+	}
+
+	// no more reports once done
+	r.Next()
+	if reports[len(reports)-1] != last {
+		t.Fatal("reported after the reader stopped")
+	}
+}
+
+func TestReaderContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewReaderContext(ctx, strings.NewReader("<a/>"))
+	if r.Next() {
+		t.Fatal("read with a done context")
+	}
+	if r.Error() != context.Canceled {
+		t.Fatalf("expected context.Canceled. Got %v", r.Error())
+	}
+}
@@ -0,0 +1,472 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrEntityLimit is returned when the expansion of the entities
+// exceeds the limit set with Reader.SetEntityLimit.
+var ErrEntityLimit = errors.New("xml: entity expansion limit exceeded")
+
+// defaultEntityLimit is the default maximum number of bytes
+// the entities can expand to in a document.
+const defaultEntityLimit = 1 << 20
+
+// EntityResolver returns the content of an external entity.
+type EntityResolver func(publicID, systemID string) (io.Reader, error)
+
+// DTD holds the document type declaration of a document.
+type DTD struct {
+	// Name is the name of the root element.
+	Name string
+	// PublicID and SystemID identify the external subset.
+	PublicID string
+	SystemID string
+	// Entities are the general entities declared in the internal subset.
+	Entities map[string]*Entity
+
+	defaults map[string]*Attrs
+}
+
+// Entity is a general entity declaration.
+type Entity struct {
+	Name string
+	// Value is the replacement text of an internal entity.
+	Value string
+	// PublicID and SystemID identify an external entity.
+	PublicID string
+	SystemID string
+
+	resolved bool
+}
+
+// External reports whether the entity is external.
+func (e *Entity) External() bool {
+	return e.SystemID != ""
+}
+
+// AttrDefaults returns the default attribute values declared for the element name.
+//
+// It returns nil if the element doesn't have default values.
+func (d *DTD) AttrDefaults(name string) *Attrs {
+	return d.defaults[name]
+}
+
+// DTD returns the document type declaration, or nil if the document
+// hasn't declared one (yet).
+func (r *Reader) DTD() *DTD {
+	return r.dtd
+}
+
+// SetEntityLimit sets the maximum number of bytes the expansion
+// of the entities declared in the DTD can produce in a document.
+//
+// Exceeding the limit stops the reader with ErrEntityLimit.
+// This protects against exponential expansions like the billion laughs attack.
+// The default limit is 1MiB. Zero disables the expansion of entities.
+func (r *Reader) SetEntityLimit(n int) {
+	r.entityLimit = n
+}
+
+// SetEntityResolver sets the function used to read external entities.
+//
+// External entities are not expanded unless a resolver is set.
+func (r *Reader) SetEntityResolver(fn EntityResolver) {
+	r.resolver = fn
+}
+
+// expand appends to dst src expanding the entities declared in the DTD.
+func (r *Reader) expand(dst, src []byte) ([]byte, error) {
+	return r.expandEntities(dst, src, nil)
+}
+
+func (r *Reader) expandEntities(dst, src []byte, stack []string) ([]byte, error) {
+	for {
+		i := bytes.IndexByte(src, '&')
+		if i < 0 {
+			break
+		}
+		dst = append(dst, src[:i]...)
+		src = src[i:]
+
+		j := bytes.IndexByte(src, ';')
+		if j < 0 {
+			break
+		}
+
+		ent := r.dtd.Entities[b2s(src[1:j])]
+		if ent == nil || (ent.External() && r.resolver == nil) {
+			dst = append(dst, src[:j+1]...)
+			src = src[j+1:]
+			continue
+		}
+
+		for _, name := range stack {
+			if name == ent.Name {
+				return dst, fmt.Errorf("xml: recursive entity %s", name)
+			}
+		}
+		if err := r.resolveEntity(ent); err != nil {
+			return dst, err
+		}
+
+		r.expanded += len(ent.Value)
+		if r.expanded > r.entityLimit {
+			return dst, ErrEntityLimit
+		}
+
+		var err error
+		dst, err = r.expandEntities(dst, []byte(ent.Value), append(stack, ent.Name))
+		if err != nil {
+			return dst, err
+		}
+		src = src[j+1:]
+	}
+
+	return append(dst, src...), nil
+}
+
+// resolveEntity reads the value of an external entity.
+func (r *Reader) resolveEntity(ent *Entity) error {
+	if !ent.External() || ent.resolved {
+		return nil
+	}
+
+	rd, err := r.resolver(ent.PublicID, ent.SystemID)
+	if err != nil {
+		return fmt.Errorf("xml: resolving entity %s: %w", ent.Name, err)
+	}
+	if c, ok := rd.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	b, err := io.ReadAll(io.LimitReader(rd, int64(r.entityLimit-r.expanded)+1))
+	if err != nil {
+		return fmt.Errorf("xml: reading entity %s: %w", ent.Name, err)
+	}
+	if len(b) > r.entityLimit-r.expanded {
+		return ErrEntityLimit
+	}
+
+	ent.Value, ent.resolved = string(b), true
+	return nil
+}
+
+// expandAttrs expands the entities of the attribute values
+// and adds the default values declared in the DTD.
+func (r *Reader) expandAttrs(s *StartElement) (err error) {
+	if len(r.dtd.Entities) > 0 && r.entityLimit > 0 {
+		for i := range s.attrs {
+			kv := &s.attrs[i]
+			if bytes.IndexByte(kv.v, '&') >= 0 {
+				r.ebuf, err = r.expand(r.ebuf[:0], kv.v)
+				if err != nil {
+					return err
+				}
+				kv.v = append(kv.v[:0], r.ebuf...)
+			}
+		}
+	}
+
+	if defs := r.dtd.defaults[b2s(s.name)]; defs != nil {
+		defs.Range(func(kv *KV) {
+			if s.attrs.lookup(b2s(kv.k)) == nil {
+				s.attrs.AddBytes(kv.k, kv.v)
+			}
+		})
+	}
+
+	return nil
+}
+
+// parseDoctype parses the document type declaration after `<!DOCTYPE`.
+func (r *Reader) parseDoctype() (err error) {
+	d := &DTD{
+		Entities: make(map[string]*Entity),
+		defaults: make(map[string]*Attrs),
+	}
+	r.dtd = d
+
+	p := dtdParser{r: r.r}
+	if d.Name, err = p.name(); err != nil {
+		return err
+	}
+	if d.PublicID, d.SystemID, err = p.externalID(); err != nil {
+		return err
+	}
+
+	c, err := p.r.skipWS()
+	if err == nil && c == '[' {
+		err = p.subset(d)
+		if err == nil {
+			c, err = p.r.skipWS()
+		}
+	}
+	if err == nil && c != '>' {
+		err = fmt.Errorf("xml: unexpected %q in DOCTYPE", c)
+	}
+
+	return err
+}
+
+// dtdParser parses the declarations of a DTD.
+type dtdParser struct {
+	r   *scanner
+	buf []byte
+}
+
+func isNameEnd(c byte) bool {
+	switch c {
+	case '>', '[', ']', '(', ')', '|', '%', ';', '"', '\'', '/', '=':
+		return true
+	}
+	return c <= 32
+}
+
+// name reads a name skipping the preceding whitespaces.
+func (p *dtdParser) name() (string, error) {
+	c, err := p.r.skipWS()
+	p.buf = p.buf[:0]
+	for err == nil && !isNameEnd(c) {
+		p.buf = append(p.buf, c)
+		c, err = p.r.ReadByte()
+	}
+	if err == nil {
+		err = p.r.UnreadByte()
+	}
+	if err == nil && len(p.buf) == 0 {
+		err = fmt.Errorf("xml: expected name in DTD. Got %q", c)
+	}
+	return string(p.buf), err
+}
+
+// literal reads a quoted string skipping the preceding whitespaces.
+func (p *dtdParser) literal() (string, error) {
+	c, err := p.r.skipWS()
+	if err != nil {
+		return "", err
+	}
+	if c != '"' && c != '\'' {
+		return "", fmt.Errorf("xml: expected quoted string in DTD. Got %q", c)
+	}
+	b, err := p.r.ReadBytes(c)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:len(b)-1]), nil
+}
+
+// peek returns the next non whitespace byte without consuming it.
+func (p *dtdParser) peek() (byte, error) {
+	c, err := p.r.skipWS()
+	if err == nil {
+		err = p.r.UnreadByte()
+	}
+	return c, err
+}
+
+// externalID reads an optional SYSTEM or PUBLIC identifier.
+func (p *dtdParser) externalID() (public, system string, err error) {
+	c, err := p.peek()
+	if err != nil || (c != 'S' && c != 'P') {
+		return "", "", err
+	}
+
+	kw, err := p.name()
+	switch {
+	case err != nil:
+	case kw == "SYSTEM":
+		system, err = p.literal()
+	case kw == "PUBLIC":
+		if public, err = p.literal(); err == nil {
+			system, err = p.literal()
+		}
+	default:
+		err = fmt.Errorf("xml: unexpected %s in DTD", kw)
+	}
+	return public, system, err
+}
+
+// subset parses the internal subset until `]`.
+func (p *dtdParser) subset(d *DTD) error {
+	for {
+		c, err := p.r.skipWS()
+		if err != nil {
+			return err
+		}
+
+		switch c {
+		case ']':
+			return nil
+		case '%': // parameter entity reference. Not expanded.
+			_, err = p.r.ReadBytes(';')
+		case '<':
+			err = p.markup(d)
+		default:
+			err = fmt.Errorf("xml: unexpected %q in DTD", c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// markup parses a markup declaration after `<`.
+func (p *dtdParser) markup(d *DTD) error {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if c == '?' {
+		p.buf, err = p.r.readUntil(p.buf[:0], "?>")
+		return err
+	}
+	if c != '!' {
+		return fmt.Errorf("xml: unexpected %q in DTD", c)
+	}
+
+	if c, err = p.r.ReadByte(); err == nil && c == '-' {
+		p.buf, err = p.r.readUntil(p.buf[:0], "-->")
+		return err
+	}
+	if err == nil {
+		err = p.r.UnreadByte()
+	}
+
+	kw, err := p.name()
+	if err != nil {
+		return err
+	}
+	switch kw {
+	case "ENTITY":
+		err = p.entity(d)
+	case "ATTLIST":
+		err = p.attlist(d)
+	default: // ELEMENT and NOTATION
+		err = p.skipDecl()
+	}
+	return err
+}
+
+func (p *dtdParser) entity(d *DTD) error {
+	c, err := p.peek()
+	if err != nil {
+		return err
+	}
+	parameter := c == '%'
+	if parameter {
+		p.r.ReadByte()
+	}
+
+	ent := &Entity{}
+	if ent.Name, err = p.name(); err != nil {
+		return err
+	}
+
+	if c, err = p.peek(); err != nil {
+		return err
+	}
+	if c == '"' || c == '\'' {
+		ent.Value, err = p.literal()
+	} else {
+		ent.PublicID, ent.SystemID, err = p.externalID()
+	}
+	if err != nil {
+		return err
+	}
+	if err = p.skipDecl(); err != nil { // NDATA
+		return err
+	}
+
+	// the first declaration is binding
+	if _, ok := d.Entities[ent.Name]; !ok && !parameter {
+		d.Entities[ent.Name] = ent
+	}
+	return nil
+}
+
+func (p *dtdParser) attlist(d *DTD) error {
+	elem, err := p.name()
+	if err != nil {
+		return err
+	}
+
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return err
+		}
+		if c == '>' {
+			p.r.ReadByte()
+			return nil
+		}
+
+		attr, err := p.name()
+		if err != nil {
+			return err
+		}
+
+		// type
+		if c, err = p.peek(); err == nil {
+			if c == '(' {
+				_, err = p.r.ReadBytes(')')
+			} else if _, err = p.name(); err == nil {
+				if c, err = p.peek(); err == nil && c == '(' { // NOTATION (...)
+					_, err = p.r.ReadBytes(')')
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		// default
+		if c, err = p.peek(); err != nil {
+			return err
+		}
+		var value string
+		if c == '#' {
+			p.r.ReadByte()
+			kw, err := p.name()
+			if err != nil {
+				return err
+			}
+			if kw != "FIXED" {
+				continue
+			}
+		}
+		if value, err = p.literal(); err != nil {
+			return err
+		}
+
+		defs := d.defaults[elem]
+		if defs == nil {
+			defs = &Attrs{}
+			d.defaults[elem] = defs
+		}
+		if defs.lookup(attr) == nil {
+			defs.Add(attr, value)
+		}
+	}
+}
+
+// skipDecl skips the rest of a declaration until `>`.
+func (p *dtdParser) skipDecl() error {
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '>':
+			return nil
+		case '"', '\'':
+			if _, err = p.r.ReadBytes(c); err != nil {
+				return err
+			}
+		}
+	}
+}
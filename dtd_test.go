@@ -0,0 +1,180 @@
+package xml
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const dtdDoc = `<?xml version="1.0"?>
+<!DOCTYPE feed SYSTEM "feed.dtd" [
+	<!-- entities -->
+	<!ENTITY company "ACME">
+	<!ENTITY full "&company; Corp.">
+	<!ENTITY company "ignored">
+	<!ENTITY % param "ignored">
+	<!ELEMENT feed (item*)>
+	<!ATTLIST item
+		lang CDATA "en"
+		kind (a|b) #REQUIRED
+		version CDATA #FIXED '1'>
+	<?pi inside ?>
+]>
+<feed><item kind="a" by="&full;">&company; and &lt;&unknown;</item><item lang="es" kind="b"/></feed>`
+
+func TestReaderDTD(t *testing.T) {
+	r := NewReader(strings.NewReader(dtdDoc))
+
+	var texts []string
+	var attrs []string
+	for r.Next() {
+		switch e := r.Element().(type) {
+		case *StartElement:
+			if e.Name() == "item" {
+				e.Attrs().Range(func(kv *KV) {
+					attrs = append(attrs, kv.Key()+"="+kv.Value())
+				})
+			}
+		case *TextElement:
+			texts = append(texts, e.String())
+		}
+	}
+	if err := r.Error(); err != io.EOF {
+		t.Fatal(err)
+	}
+
+	d := r.DTD()
+	if d == nil {
+		t.Fatal("missing DTD")
+	}
+	if d.Name != "feed" || d.SystemID != "feed.dtd" {
+		t.Fatalf("unexpected DTD %s %q", d.Name, d.SystemID)
+	}
+	if v := d.Entities["company"].Value; v != "ACME" {
+		t.Fatalf("unexpected entity value %q", v)
+	}
+	if _, ok := d.Entities["param"]; ok {
+		t.Fatal("parameter entity declared as general entity")
+	}
+
+	expectedTexts := []string{"ACME and &lt;&unknown;"}
+	if strings.Join(texts, "|") != strings.Join(expectedTexts, "|") {
+		t.Fatalf("unexpected texts %q", texts)
+	}
+
+	expectedAttrs := []string{
+		"kind=a", "by=ACME Corp.", "lang=en", "version=1",
+		"lang=es", "kind=b", "version=1",
+	}
+	if strings.Join(attrs, "|") != strings.Join(expectedAttrs, "|") {
+		t.Fatalf("unexpected attrs %q", attrs)
+	}
+}
+
+func TestReaderEntityLimit(t *testing.T) {
+	const laughs = `<!DOCTYPE lolz [
+	<!ENTITY lol "lol">
+	<!ENTITY lol1 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+	<!ENTITY lol2 "&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;">
+	<!ENTITY lol3 "&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;">
+	<!ENTITY lol4 "&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;">
+]><lolz>&lol4;</lolz>`
+
+	r := NewReader(strings.NewReader(laughs))
+	r.SetEntityLimit(1024)
+	for r.Next() {
+	}
+	if err := r.Error(); err != ErrEntityLimit {
+		t.Fatalf("expected ErrEntityLimit. Got %v", err)
+	}
+
+	r = NewReader(strings.NewReader(laughs))
+	r.SetEntityLimit(0)
+	var text string
+	for r.Next() {
+		if e, ok := r.Element().(*TextElement); ok {
+			text = e.String()
+		}
+	}
+	if text != "&lol4;" {
+		t.Fatalf("expected the entity unexpanded. Got %q", text)
+	}
+}
+
+func TestReaderRecursiveEntity(t *testing.T) {
+	const str = `<!DOCTYPE a [<!ENTITY a "&b;"><!ENTITY b "&a;">]><a>&a;</a>`
+
+	r := NewReader(strings.NewReader(str))
+	for r.Next() {
+	}
+	if err := r.Error(); err == io.EOF || err == nil {
+		t.Fatal("expected recursion error")
+	}
+}
+
+func TestReaderExternalEntity(t *testing.T) {
+	const str = `<!DOCTYPE a [<!ENTITY ext SYSTEM "file:///etc/passwd">]><a>&ext;</a>`
+
+	read := func(r *Reader) (string, error) {
+		var text string
+		for r.Next() {
+			if e, ok := r.Element().(*TextElement); ok {
+				text = e.String()
+			}
+		}
+		return text, r.Error()
+	}
+
+	text, _ := read(NewReader(strings.NewReader(str)))
+	if text != "&ext;" {
+		t.Fatalf("external entity expanded without resolver: %q", text)
+	}
+
+	r := NewReader(strings.NewReader(str))
+	r.SetEntityResolver(func(publicID, systemID string) (io.Reader, error) {
+		if systemID != "file:///etc/passwd" {
+			t.Fatalf("unexpected system id %q", systemID)
+		}
+		return strings.NewReader("resolved"), nil
+	})
+	if text, _ = read(r); text != "resolved" {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	errResolve := errors.New("denied")
+	r = NewReader(strings.NewReader(str))
+	r.SetEntityResolver(func(publicID, systemID string) (io.Reader, error) {
+		return nil, errResolve
+	})
+	if _, err := read(r); !errors.Is(err, errResolve) {
+		t.Fatalf("expected resolver error. Got %v", err)
+	}
+}
+
+func TestReaderCommentsAndCDATA(t *testing.T) {
+	const str = `<a><!-- a > comment --><![CDATA[ <b> ]]><?pi a > b ?><c/></a>`
+
+	var names []string
+	r := NewReader(strings.NewReader(str))
+	for r.Next() {
+		if e, ok := r.Element().(*StartElement); ok {
+			names = append(names, e.Name())
+		}
+	}
+	if err := r.Error(); err != io.EOF {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "a,c" {
+		t.Fatalf("unexpected elements %q", names)
+	}
+}
+
+func TestReaderUnknownDirective(t *testing.T) {
+	const str = `<root><!DATA x><a/><b>OCTYPE</b></root>`
+
+	got := readAll(NewReader(strings.NewReader(str)))
+	if expected := `<root><a/><b>OCTYPE</b></root>`; got != expected {
+		t.Fatalf("got %s. Expected %s", got, expected)
+	}
+}
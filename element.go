@@ -1,14 +1,15 @@
 package xml
 
-import "bufio"
-
 // Element represents a XML element.
 //
 // Element can be:
 // - StartElement.
 // - EndElement.
 // - TextElement.
+//
+// Other implementations can be read with a Reader created by NewSourceReader.
 type Element interface {
-	parse(r *bufio.Reader) error
+	Kind() Kind
 	String() string
+	Raw() []byte
 }
@@ -1,8 +1,7 @@
 package xml
 
 import (
-	"bufio"
-	"fmt"
+	"io"
 	"sync"
 )
 
@@ -20,7 +19,12 @@ func releaseEnd(end *EndElement) {
 
 // EndElement represents a XML end element.
 type EndElement struct {
-	name []byte
+	name      []byte
+	raw       []byte
+	synthetic bool
+
+	id    Name   // handle of the interned name (see Reader.SetNames).
+	iname string // interned name.
 }
 
 // NewEnd creates a new EndElement.
@@ -32,7 +36,17 @@ func NewEnd(name string) *EndElement {
 
 // String returns the string representation of EndElement.
 func (e *EndElement) String() string {
-	return fmt.Sprintf("</%s>", e.name)
+	return string(e.AppendXML(nil))
+}
+
+// AppendXML appends the XML representation of the element to dst.
+func (e *EndElement) AppendXML(dst []byte) []byte {
+	return append(append(append(dst, '<', '/'), e.name...), '>')
+}
+
+// WriteTo writes the XML representation of the element to w.
+func (e *EndElement) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, e)
 }
 
 // SetName sets the name to the end element.
@@ -45,15 +59,48 @@ func (e *EndElement) SetNameBytes(name []byte) {
 	e.name = append(e.name[:0], name...)
 }
 
+// Reset sets the default values to the EndElement.
 func (e *EndElement) Reset() {
 	e.name = e.name[:0]
+	e.raw = e.raw[:0]
+	e.synthetic = false
+	e.id, e.iname = 0, ""
+}
+
+// IsSynthetic reports whether the EndElement wasn't in the input
+// but has been created by the Reader to close an element
+// (see Reader.SetRepair and Reader.SetHTML).
+func (e *EndElement) IsSynthetic() bool {
+	return e.synthetic
+}
+
+// Raw returns the bytes the element has been read from
+// including any whitespace or comment preceding it.
+//
+// Raw is only filled when the Reader keeps the raw bytes (see Reader.SetKeepRaw).
+func (e *EndElement) Raw() []byte {
+	return e.raw
 }
 
 // Name returns the name of the XML node.
+//
+// The name is not allocated if it has been interned by the Reader (see Reader.SetNames).
 func (e *EndElement) Name() string {
+	if e.iname == b2s(e.name) {
+		return e.iname
+	}
 	return string(e.name)
 }
 
+// NameID returns the handle of the name interned by the Reader,
+// or zero if the name is not interned (see Reader.SetNames).
+func (e *EndElement) NameID() Name {
+	if e.id != 0 && e.iname == b2s(e.name) {
+		return e.id
+	}
+	return 0
+}
+
 // NameBytes returns the name of the XML node in bytes.
 func (e *EndElement) NameBytes() []byte {
 	return e.name
@@ -66,26 +113,23 @@ func (e *EndElement) NameUnsafe() string {
 	return b2s(e.name)
 }
 
-func (e *EndElement) parse(r *bufio.Reader) error {
+// Kind returns EndKind.
+func (e *EndElement) Kind() Kind {
+	return EndKind
+}
+
+func (e *EndElement) parse(r *scanner) error {
 	e.Reset()
 
-	c, err := skipWS(r)
+	_, err := r.skipWS()
 	if err != nil {
 		return err
 	}
-	e.name = append(e.name, c)
-	for {
-		c, err = r.ReadByte()
-		if err != nil {
-			break
-		}
-		if c == '>' {
-			break
-		}
-		if c == ' ' {
-			continue
-		}
-		e.name = append(e.name, c)
+	r.UnreadByte()
+
+	e.name, err = r.readName(e.name)
+	if err == nil {
+		_, err = r.ReadBytes('>')
 	}
 
 	return err
@@ -0,0 +1,165 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrStop can be returned by the callbacks of a Handler
+// to stop parsing without reporting an error.
+var ErrStop = errors.New("xml: stop parsing")
+
+// Handler receives the events of a document parsed with Parse.
+//
+// The elements and byte slices passed to the callbacks are only valid
+// during the call, as they are reused for the next event.
+// Returning an error from a callback stops the parsing.
+type Handler interface {
+	StartElement(e *StartElement) error
+	EndElement(e *EndElement) error
+	Text(e *TextElement) error
+	// CDATA receives the content of a CDATA section.
+	CDATA(data []byte) error
+	// Comment receives the text of a comment.
+	Comment(text []byte) error
+	// ProcInst receives a processing instruction like `<?target inst?>`.
+	ProcInst(target, inst []byte) error
+	// Error receives the error stopping the parsing, if any.
+	Error(err error)
+}
+
+// HandlerFuncs implements Handler calling the functions that are set.
+type HandlerFuncs struct {
+	OnStartElement func(e *StartElement) error
+	OnEndElement   func(e *EndElement) error
+	OnText         func(e *TextElement) error
+	OnCDATA        func(data []byte) error
+	OnComment      func(text []byte) error
+	OnProcInst     func(target, inst []byte) error
+	OnError        func(err error)
+}
+
+// StartElement calls OnStartElement if it is set.
+func (h *HandlerFuncs) StartElement(e *StartElement) error {
+	if h.OnStartElement == nil {
+		return nil
+	}
+	return h.OnStartElement(e)
+}
+
+// EndElement calls OnEndElement if it is set.
+func (h *HandlerFuncs) EndElement(e *EndElement) error {
+	if h.OnEndElement == nil {
+		return nil
+	}
+	return h.OnEndElement(e)
+}
+
+// Text calls OnText if it is set.
+func (h *HandlerFuncs) Text(e *TextElement) error {
+	if h.OnText == nil {
+		return nil
+	}
+	return h.OnText(e)
+}
+
+// CDATA calls OnCDATA if it is set.
+func (h *HandlerFuncs) CDATA(data []byte) error {
+	if h.OnCDATA == nil {
+		return nil
+	}
+	return h.OnCDATA(data)
+}
+
+// Comment calls OnComment if it is set.
+func (h *HandlerFuncs) Comment(text []byte) error {
+	if h.OnComment == nil {
+		return nil
+	}
+	return h.OnComment(text)
+}
+
+// ProcInst calls OnProcInst if it is set.
+func (h *HandlerFuncs) ProcInst(target, inst []byte) error {
+	if h.OnProcInst == nil {
+		return nil
+	}
+	return h.OnProcInst(target, inst)
+}
+
+// Error calls OnError if it is set.
+func (h *HandlerFuncs) Error(err error) {
+	if h.OnError != nil {
+		h.OnError(err)
+	}
+}
+
+// Parse reads the document from r calling the callbacks of h.
+//
+// Parse returns nil when the document has been read completely
+// or a callback returned ErrStop.
+func Parse(r io.Reader, h Handler) error {
+	return NewReader(r).Parse(h)
+}
+
+// Parse reads the rest of the document calling the callbacks of h
+// using the settings of the reader.
+//
+// See the Parse function.
+func (r *Reader) Parse(h Handler) (err error) {
+	r.handler = h
+	defer func() {
+		r.handler = nil
+	}()
+
+	for err == nil && r.Next() {
+		switch e := r.e.(type) {
+		case *StartElement:
+			err = h.StartElement(e)
+			if err == nil && e.HasEnd() {
+				end := endPool.Get().(*EndElement)
+				end.name = append(end.name[:0], e.name...)
+				err = h.EndElement(end)
+				releaseEnd(end)
+			}
+		case *EndElement:
+			err = h.EndElement(e)
+		case *TextElement:
+			err = h.Text(e)
+		}
+	}
+	if err == nil {
+		err = r.err
+	}
+
+	switch err {
+	case nil, io.EOF, ErrStop:
+		return nil
+	}
+	h.Error(err)
+	return err
+}
+
+// comment passes the comment read into r.buf to the handler.
+func (r *Reader) comment() error {
+	return r.handler.Comment(r.buf)
+}
+
+// cdata passes the CDATA section read into r.buf to the handler.
+func (r *Reader) cdata() error {
+	const prefix = "CDATA["
+	if !bytes.HasPrefix(r.buf, []byte(prefix)) {
+		return nil
+	}
+	return r.handler.CDATA(r.buf[len(prefix):])
+}
+
+// procInst passes the processing instruction read into r.buf to the handler.
+func (r *Reader) procInst() error {
+	target, inst := r.buf, r.buf[len(r.buf):]
+	if i := bytes.IndexFunc(r.buf, func(c rune) bool { return c <= 32 }); i >= 0 {
+		target, inst = r.buf[:i], trimWS(r.buf[i:])
+	}
+	return r.handler.ProcInst(target, inst)
+}
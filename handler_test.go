@@ -0,0 +1,131 @@
+package xml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	b   strings.Builder
+	err error
+}
+
+func (h *recorder) StartElement(e *StartElement) error {
+	h.b.WriteString("start:" + e.Name() + " ")
+	return nil
+}
+
+func (h *recorder) EndElement(e *EndElement) error {
+	h.b.WriteString("end:" + e.Name() + " ")
+	return nil
+}
+
+func (h *recorder) Text(e *TextElement) error {
+	h.b.WriteString("text:" + e.String() + " ")
+	return nil
+}
+
+func (h *recorder) CDATA(data []byte) error {
+	h.b.WriteString("cdata:" + string(data) + " ")
+	return nil
+}
+
+func (h *recorder) Comment(text []byte) error {
+	h.b.WriteString("comment:" + string(text) + " ")
+	return nil
+}
+
+func (h *recorder) ProcInst(target, inst []byte) error {
+	h.b.WriteString("pi:" + string(target) + "=" + string(inst) + " ")
+	return nil
+}
+
+func (h *recorder) Error(err error) {
+	h.err = err
+}
+
+func TestParse(t *testing.T) {
+	const str = `<?xml version="1.0"?><doc><!-- note --><a x="1">hi</a><![CDATA[<raw>]]><b/><?pi?></doc>`
+
+	h := &recorder{}
+	if err := Parse(strings.NewReader(str), h); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `pi:xml=version="1.0" start:doc comment: note  start:a text:hi end:a ` +
+		`cdata:<raw> start:b end:b pi:pi= end:doc `
+	if h.b.String() != expected {
+		t.Fatalf("\n%s\nexpected:\n%s", h.b.String(), expected)
+	}
+	if h.err != nil {
+		t.Fatalf("unexpected error %v", h.err)
+	}
+}
+
+func TestParseStop(t *testing.T) {
+	const str = `<doc><a/><b/><c/></doc>`
+
+	var names []string
+	err := Parse(strings.NewReader(str), &HandlerFuncs{
+		OnStartElement: func(e *StartElement) error {
+			names = append(names, e.Name())
+			if e.Name() == "b" {
+				return ErrStop
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "doc,a,b" {
+		t.Fatalf("unexpected elements %q", names)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	errText := errors.New("text")
+
+	var got error
+	err := Parse(strings.NewReader(`<doc>text</doc>`), &HandlerFuncs{
+		OnText: func(e *TextElement) error {
+			return errText
+		},
+		OnError: func(err error) {
+			got = err
+		},
+	})
+	if err != errText || got != errText {
+		t.Fatalf("expected text error. Got %v and %v", err, got)
+	}
+}
+
+func TestParseMalformedComments(t *testing.T) {
+	var comments []string
+	h := &HandlerFuncs{
+		OnComment: func(text []byte) error {
+			comments = append(comments, string(text))
+			return nil
+		},
+	}
+
+	// unterminated: the rest of the input is the comment
+	if err := Parse(strings.NewReader(`<a><!---></a>`), h); err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 0 {
+		t.Fatalf("unexpected comments %q", comments)
+	}
+
+	if err := Parse(strings.NewReader(`<a><!-x></a>`), h); err == nil {
+		t.Fatal("expected a syntax error")
+	}
+
+	if err := Parse(strings.NewReader(`<a><!----></a>`), h); err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0] != "" {
+		t.Fatalf("unexpected comments %q", comments)
+	}
+}
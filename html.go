@@ -0,0 +1,324 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+)
+
+// SetHTML makes the reader parse tag soup HTML.
+//
+// In HTML mode the element and attribute names are lowercased,
+// the attribute values can be unquoted or missing, the void elements
+// like <br> are reported as self closed, the content of <script> and <style>
+// is read as text and the named HTML entities are replaced
+// by the characters they represent.
+//
+// The reader keeps track of the open elements, reporting EndElements
+// for the elements closed implicitly, like a <li> followed by another <li>,
+// and for the elements still open at EOF. End tags without
+// a matching open element are dropped.
+func (r *Reader) SetHTML(html bool) {
+	r.html = html
+	r.r.html = html
+}
+
+// htmlVoid are the elements that never have content.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawText are the elements whose content is text.
+var htmlRawText = map[string]bool{
+	"script": true, "style": true,
+}
+
+var (
+	htmlCell  = []string{"td", "th", "tr", "thead", "tbody", "tfoot"}
+	htmlBlock = []string{
+		"address", "article", "aside", "blockquote", "details", "div", "dl",
+		"fieldset", "figcaption", "figure", "footer", "form", "h1", "h2", "h3",
+		"h4", "h5", "h6", "header", "hr", "main", "menu", "nav", "ol", "p",
+		"pre", "section", "table", "ul",
+	}
+)
+
+// htmlClosedBy holds for an open element the start tags that close it implicitly.
+var htmlClosedBy = map[string][]string{
+	"li":       {"li"},
+	"dt":       {"dt", "dd"},
+	"dd":       {"dt", "dd"},
+	"p":        htmlBlock,
+	"option":   {"option", "optgroup"},
+	"optgroup": {"optgroup"},
+	"tr":       {"tr", "thead", "tbody", "tfoot"},
+	"td":       htmlCell,
+	"th":       htmlCell,
+	"thead":    {"tbody", "tfoot"},
+	"tbody":    {"tbody", "tfoot"},
+}
+
+func closedBy(open, name []byte) bool {
+	for _, s := range htmlClosedBy[b2s(open)] {
+		if s == b2s(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerASCII lowercases b in place.
+func lowerASCII(b []byte) []byte {
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return b
+}
+
+// parseHTML parses an HTML attribute, which can be unquoted or have no value.
+//
+// It returns false if no attribute has been found.
+func (kv *KV) parseHTML(r *scanner) (bool, error) {
+	k, err := r.readName(kv.k[:0])
+	if err != nil {
+		return false, err
+	}
+	if len(k) == 0 { // stray character
+		_, err = r.ReadByte()
+		return false, err
+	}
+	kv.k = lowerASCII(k)
+	kv.v = kv.v[:0]
+
+	c, err := r.skipWS()
+	if err != nil {
+		return false, err
+	}
+	if c != '=' { // no value
+		return true, r.UnreadByte()
+	}
+
+	c, err = r.skipWS()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case '"', '\'':
+		var v []byte
+		if v, err = r.ReadBytes(c); err == nil {
+			kv.v = normalizeAttr(append(kv.v, v[:len(v)-1]...))
+		}
+	default:
+		r.UnreadByte()
+		for err == nil {
+			if c, err = r.ReadByte(); err == nil {
+				if c <= ' ' || c == '>' {
+					err = r.UnreadByte()
+					break
+				}
+				kv.v = append(kv.v, c)
+			}
+		}
+	}
+	return err == nil, err
+}
+
+// balance keeps the stack of open elements for the element just read,
+// queueing the EndElements of the elements closed implicitly.
+//
+// Unmatched EndElements are dropped setting r.e to nil.
+func (r *Reader) balance() {
+	switch e := r.e.(type) {
+	case *StartElement:
+		if r.html {
+			lowerASCII(e.name)
+			if htmlVoid[b2s(e.name)] {
+				e.hasEnd = true
+			}
+			for len(r.stack) > 0 && closedBy(r.stack[len(r.stack)-1], e.name) {
+				r.queueEnd()
+			}
+			e.attrs.Range(func(kv *KV) {
+				kv.v = unescapeHTML(kv.v[:0], kv.v)
+			})
+		}
+		if !e.hasEnd {
+			r.pushOpen(e.name)
+			r.rawText = r.html && htmlRawText[b2s(e.name)]
+		}
+	case *EndElement:
+		if r.html {
+			lowerASCII(e.name)
+		}
+
+		i := len(r.stack) - 1
+		for i >= 0 && !bytes.Equal(r.stack[i], e.name) {
+			i--
+		}
+		if i < 0 { // not open
+			r.repaired(RepairUnmatchedEnd, e.name)
+			releaseEnd(e)
+			r.e = nil
+			return
+		}
+		for len(r.stack)-1 > i {
+			r.repaired(RepairMissingEnd, r.stack[len(r.stack)-1])
+			r.queueEnd()
+		}
+		r.stack = r.stack[:i]
+	}
+
+	if len(r.queue) > 0 {
+		// the element goes after the EndElements queued
+		if r.keepRaw {
+			r.setRaw(r.e)
+		}
+		r.queue = append(r.queue, r.e)
+		r.e = r.dequeue()
+	}
+}
+
+// pushOpen adds name to the stack of open elements.
+func (r *Reader) pushOpen(name []byte) {
+	n := len(r.stack)
+	if n < cap(r.stack) {
+		r.stack = r.stack[:n+1]
+	} else {
+		r.stack = append(r.stack, nil)
+	}
+	r.stack[n] = append(r.stack[n][:0], name...)
+}
+
+// queueEnd pops the last open element queueing its EndElement.
+func (r *Reader) queueEnd() {
+	name := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+
+	e := endPool.Get().(*EndElement)
+	e.Reset()
+	e.name = append(e.name, name...)
+	e.synthetic = true
+	r.queue = append(r.queue, e)
+}
+
+func (r *Reader) dequeue() Element {
+	e := r.queue[0]
+	r.queue = r.queue[:copy(r.queue, r.queue[1:])]
+	return e
+}
+
+// closeOpen queues the EndElements of the elements open at EOF.
+func (r *Reader) closeOpen() {
+	for len(r.stack) > 0 {
+		r.repaired(RepairUnclosed, r.stack[len(r.stack)-1])
+		r.queueEnd()
+	}
+	if len(r.queue) > 0 {
+		r.err = nil
+		r.e = r.dequeue()
+	}
+}
+
+// readRawText reads the content of a raw text element until its end tag.
+func (r *Reader) readRawText() (err error) {
+	name := r.stack[len(r.stack)-1]
+	r.buf = r.buf[:0]
+	for {
+		if r.buf, err = r.r.readText(r.buf); err != nil {
+			return err
+		}
+
+		b, err := r.r.peek(len(name) + 2)
+		if err == nil && b[1] == '/' && bytes.EqualFold(b[2:], name) {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		c, _ := r.r.ReadByte() // '<'
+		r.buf = append(r.buf, c)
+	}
+}
+
+// unescapeHTML appends to dst src replacing the named HTML entities
+// by the characters they represent.
+//
+// The XML predefined entities and the character references are kept.
+// The characters are never longer than their entities, so dst can be src[:0].
+func unescapeHTML(dst, src []byte) []byte {
+	for {
+		i := bytes.IndexByte(src, '&')
+		if i < 0 {
+			break
+		}
+		dst = append(dst, src[:i]...)
+		src = src[i:]
+
+		j := 1
+		for j < len(src) && j < 10 && isAlnum(src[j]) {
+			j++
+		}
+		if j < len(src) && src[j] == ';' {
+			if s, ok := htmlEntities[b2s(src[1:j])]; ok {
+				dst = append(dst, s...)
+				src = src[j+1:]
+				continue
+			}
+		}
+		dst = append(dst, '&')
+		src = src[1:]
+	}
+	return append(dst, src...)
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// htmlEntities are the most common named HTML entities.
+var htmlEntities = map[string]string{
+	"nbsp": "\u00a0", "iexcl": "¡", "cent": "¢", "pound": "£", "curren": "¤",
+	"yen": "¥", "brvbar": "¦", "sect": "§", "uml": "¨", "copy": "©",
+	"ordf": "ª", "laquo": "«", "not": "¬", "shy": "\u00ad", "reg": "®",
+	"macr": "¯", "deg": "°", "plusmn": "±", "sup2": "²", "sup3": "³",
+	"acute": "´", "micro": "µ", "para": "¶", "middot": "·", "cedil": "¸",
+	"sup1": "¹", "ordm": "º", "raquo": "»", "frac14": "¼", "frac12": "½",
+	"frac34": "¾", "iquest": "¿", "times": "×", "divide": "÷",
+	"Agrave": "À", "Aacute": "Á", "Acirc": "Â", "Atilde": "Ã", "Auml": "Ä",
+	"Aring": "Å", "AElig": "Æ", "Ccedil": "Ç", "Egrave": "È", "Eacute": "É",
+	"Ecirc": "Ê", "Euml": "Ë", "Igrave": "Ì", "Iacute": "Í", "Icirc": "Î",
+	"Iuml": "Ï", "ETH": "Ð", "Ntilde": "Ñ", "Ograve": "Ò", "Oacute": "Ó",
+	"Ocirc": "Ô", "Otilde": "Õ", "Ouml": "Ö", "Oslash": "Ø", "Ugrave": "Ù",
+	"Uacute": "Ú", "Ucirc": "Û", "Uuml": "Ü", "Yacute": "Ý", "THORN": "Þ",
+	"szlig": "ß", "agrave": "à", "aacute": "á", "acirc": "â", "atilde": "ã",
+	"auml": "ä", "aring": "å", "aelig": "æ", "ccedil": "ç", "egrave": "è",
+	"eacute": "é", "ecirc": "ê", "euml": "ë", "igrave": "ì", "iacute": "í",
+	"icirc": "î", "iuml": "ï", "eth": "ð", "ntilde": "ñ", "ograve": "ò",
+	"oacute": "ó", "ocirc": "ô", "otilde": "õ", "ouml": "ö", "oslash": "ø",
+	"ugrave": "ù", "uacute": "ú", "ucirc": "û", "uuml": "ü", "yacute": "ý",
+	"thorn": "þ", "yuml": "ÿ", "OElig": "Œ", "oelig": "œ", "Scaron": "Š",
+	"scaron": "š", "Yuml": "Ÿ", "fnof": "ƒ", "circ": "ˆ", "tilde": "˜",
+	"Alpha": "Α", "Beta": "Β", "Gamma": "Γ", "Delta": "Δ", "Omega": "Ω",
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε",
+	"lambda": "λ", "mu": "μ", "pi": "π", "sigma": "σ", "omega": "ω",
+	"ensp": "\u2002", "emsp": "\u2003", "thinsp": "\u2009", "zwnj": "\u200c",
+	"zwj": "\u200d", "lrm": "\u200e", "rlm": "\u200f", "ndash": "–",
+	"mdash": "—", "lsquo": "‘", "rsquo": "’", "sbquo": "‚", "ldquo": "“",
+	"rdquo": "”", "bdquo": "„", "dagger": "†", "Dagger": "‡", "bull": "•",
+	"hellip": "…", "permil": "‰", "prime": "′", "Prime": "″", "lsaquo": "‹",
+	"rsaquo": "›", "oline": "‾", "frasl": "⁄", "euro": "€", "trade": "™",
+	"larr": "←", "uarr": "↑", "rarr": "→", "darr": "↓", "harr": "↔",
+	"lArr": "⇐", "rArr": "⇒", "hArr": "⇔", "forall": "∀", "part": "∂",
+	"exist": "∃", "empty": "∅", "nabla": "∇", "isin": "∈", "notin": "∉",
+	"prod": "∏", "sum": "∑", "minus": "−", "lowast": "∗", "radic": "√",
+	"prop": "∝", "infin": "∞", "ang": "∠", "and": "∧", "or": "∨",
+	"cap": "∩", "cup": "∪", "int": "∫", "there4": "∴", "sim": "∼",
+	"cong": "≅", "asymp": "≈", "ne": "≠", "equiv": "≡", "le": "≤",
+	"ge": "≥", "sub": "⊂", "sup": "⊃", "sube": "⊆", "supe": "⊇",
+	"oplus": "⊕", "otimes": "⊗", "perp": "⊥", "sdot": "⋅", "loz": "◊",
+	"spades": "♠", "clubs": "♣", "hearts": "♥", "diams": "♦", "check": "✓",
+}
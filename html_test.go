@@ -0,0 +1,134 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func readHTML(str string) string {
+	r := NewReader(strings.NewReader(str))
+	r.SetHTML(true)
+	return readAll(r)
+}
+
+func TestReaderHTML(t *testing.T) {
+	const page = `<!DOCTYPE html>
+<HTML>
+<Head><Title>Portal</Title>
+<script type=text/javascript>if (a < b && c) { x = "</p>"; }</script>
+<STYLE>p > a { color: red }</STYLE>
+</head>
+<body class=main>
+<p>First &copy; 2024 &amp; more &unknown;<br>line<BR/>
+<p>Second <img src="a.png" alt=logo>
+<ul><li>one<li>two &mdash; 2</ul>
+<table><tr><td>a<td>b<tr><td>c</table>
+<input type=checkbox checked disabled>
+<a href=/x?a=1&b=2 title='it&apos;s'>link</a></span>
+</body>`
+
+	const expected = `<html><head><title>Portal</title>` +
+		`<script type=text/javascript>if (a < b && c) { x = "</p>"; }</script>` +
+		`<style>p > a { color: red }</style></head>` +
+		`<body class=main><p>First © 2024 &amp; more &unknown;<br/>line<br/></p>` +
+		`<p>Second <img src=a.png alt=logo/></p>` +
+		`<ul><li>one</li><li>two — 2</li></ul>` +
+		`<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>` +
+		`<input type=checkbox checked= disabled=/>` +
+		`<a href=/x?a=1&b=2 title=it&apos;s>link</a></body></html>`
+
+	if got := readHTML(page); got != expected {
+		t.Fatalf("\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestReaderHTMLSynthetic(t *testing.T) {
+	r := NewReader(strings.NewReader("<ul><li>one<li>two</ul>"))
+	r.SetHTML(true)
+
+	var ends []string
+	for r.Next() {
+		if e, ok := r.Element().(*EndElement); ok {
+			if e.synthetic {
+				ends = append(ends, "~"+e.Name())
+			} else {
+				ends = append(ends, e.Name())
+			}
+		}
+	}
+	if strings.Join(ends, ",") != "~li,~li,ul" {
+		t.Fatalf("unexpected ends %q", ends)
+	}
+}
+
+func TestReaderHTMLAttrQuote(t *testing.T) {
+	for _, str := range []string{`<a x=a"b>`, `<a x='a"b'>`} {
+		r := NewReader(strings.NewReader(str))
+		r.SetHTML(true)
+		if !r.Next() {
+			t.Fatal(r.Error())
+		}
+		s := r.Element().(*StartElement)
+		if v := s.Attrs().Get("x").Value(); v != `a"b` {
+			t.Fatalf("%s: unexpected value %q", str, v)
+		}
+		if got := string(s.AppendXML(nil)); got != `<a x="a&quot;b">` {
+			t.Fatalf("%s: unexpected output %s", str, got)
+		}
+	}
+}
+
+func TestReaderHTMLKeepRaw(t *testing.T) {
+	const str = "<ul>\n<li>one\n<li>two</ul>"
+
+	r := NewReader(strings.NewReader(str))
+	r.SetHTML(true)
+	r.SetKeepRaw(true)
+
+	var raw []byte
+	for r.Next() {
+		raw = append(raw, r.Raw()...)
+	}
+	if string(raw) != str {
+		t.Fatalf("unexpected raw bytes %q", raw)
+	}
+}
+
+func TestUnescapeHTMLInPlace(t *testing.T) {
+	for name, s := range htmlEntities {
+		if len(s) > len(name)+2 {
+			t.Fatalf("&%s; is shorter than %q", name, s)
+		}
+	}
+
+	b := []byte("&nbsp;a&amp;&hellip;&#38;&bogus;&")
+	if got := string(unescapeHTML(b[:0], b)); got != "\u00a0a&amp;…&#38;&bogus;&" {
+		t.Fatalf("unexpected %q", got)
+	}
+}
+
+func TestParserHTMLRawText(t *testing.T) {
+	const str = `<p><script>if (a < b) { x = "<p>"; }</script>done</p>`
+
+	r := NewReader(strings.NewReader(str))
+	r.SetHTML(true)
+	expected := readAll(r)
+
+	for i := 0; i <= len(str); i++ {
+		var b strings.Builder
+		p := NewParser(collect(&b))
+		p.Reader().SetHTML(true)
+		if err := p.Feed([]byte(str[:i])); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Feed([]byte(str[i:])); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Fatalf("split at %d:\n%s\nexpected:\n%s", i, b.String(), expected)
+		}
+	}
+}
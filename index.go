@@ -0,0 +1,82 @@
+package xml
+
+// minIndexedAttrs is the number of attributes from which
+// StartElement.Attr uses a hash index instead of a linear scan.
+const minIndexedAttrs = 16
+
+// maxIndexedAttrs is the maximum number of attributes an index can hold.
+const maxIndexedAttrs = 1<<16 - 1
+
+// attrIndex is an open addressing hash table mapping
+// attribute keys to their position in Attrs.
+type attrIndex struct {
+	slots []uint16 // position + 1. 0 means empty.
+	n     int      // number of attributes indexed.
+	gen   uint32   // generation of the attributes indexed (see Attrs.gen).
+}
+
+func (idx *attrIndex) reset() {
+	idx.n = 0
+}
+
+func (idx *attrIndex) build(kvs Attrs) {
+	size := 32
+	for size < 2*len(kvs) {
+		size <<= 1
+	}
+	if cap(idx.slots) < size {
+		idx.slots = make([]uint16, size)
+	} else {
+		idx.slots = idx.slots[:size]
+		for i := range idx.slots {
+			idx.slots[i] = 0
+		}
+	}
+
+	mask := uint32(size - 1)
+	for i := range kvs {
+		h := hashKey(b2s(kvs[i].k)) & mask
+		for idx.slots[h] != 0 {
+			h = (h + 1) & mask
+		}
+		idx.slots[h] = uint16(i + 1)
+	}
+	idx.n = len(kvs)
+	idx.gen = kvs.gen()
+}
+
+// get returns the attribute called name or nil.
+//
+// The attributes can be modified through a retained Attrs
+// without resetting the index, so it is rebuilt when
+// the generation of the attributes differs from the indexed one.
+func (idx *attrIndex) get(kvs Attrs, name string) *KV {
+	if len(kvs) > maxIndexedAttrs {
+		return kvs.lookup(name)
+	}
+	if idx.n != len(kvs) || idx.gen != kvs.gen() {
+		idx.build(kvs)
+	}
+	return idx.find(kvs, name)
+}
+
+func (idx *attrIndex) find(kvs Attrs, name string) *KV {
+	mask := uint32(len(idx.slots) - 1)
+	for h := hashKey(name) & mask; idx.slots[h] != 0; h = (h + 1) & mask {
+		kv := &kvs[idx.slots[h]-1]
+		if b2s(kv.k) == name {
+			return kv
+		}
+	}
+	return nil
+}
+
+// hashKey computes the FNV-1a hash of s.
+func hashKey(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
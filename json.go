@@ -0,0 +1,468 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// JSONConvention defines how XML is mapped to JSON.
+type JSONConvention uint8
+
+const (
+	// JSONAttrText maps the attributes to "@name" keys and the text
+	// of elements with attributes or children to a "#text" key.
+	// Elements with only text are mapped to strings and empty elements to null.
+	JSONAttrText JSONConvention = iota
+	// JSONBadgerFish maps every element to an object holding
+	// the attributes as "@name" keys and the text as a "$" key.
+	JSONBadgerFish
+	// JSONParker drops the root element and the attributes.
+	// Elements with only text are mapped to strings, empty elements to null,
+	// and the text of elements with children is dropped.
+	JSONParker
+)
+
+// jsonFlushSize is the size from which the JSON output is written.
+const jsonFlushSize = 32 << 10
+
+// jsonHoldLimit is the maximum size of the output held
+// while it is not known whether an element starts an array.
+const jsonHoldLimit = 1 << 20
+
+// JSONConverter converts XML documents to JSON and back.
+//
+// Consecutive sibling elements with the same name are mapped
+// to arrays. Both directions stream: only the first element of a run
+// of siblings is held until it is known whether it starts an array,
+// so large arrays of repeating elements don't build up in memory.
+// Siblings with the same name that are not consecutive produce duplicated keys.
+//
+// The output held is limited to 1MiB: an element whose content is bigger
+// than that, like a wrapper of the rest of the document, is written
+// as a single value, so a run of such siblings produces duplicated keys too.
+type JSONConverter struct {
+	conv JSONConvention
+	root string
+
+	out    []byte
+	frames []jsonFrame
+	holds  int // number of frames holding the output.
+	buf    []byte
+}
+
+type jsonFrame struct {
+	skip  bool
+	open  bool   // the object of the element has been written.
+	keys  int    // number of keys written in the object.
+	last  []byte // name of the last child.
+	array bool   // the children named last are being written as an array.
+	// hold is the position in the output of the value of the last child
+	// while it is not known whether it starts an array, or -1.
+	hold int
+	text []byte
+}
+
+// NewJSONConverter creates a JSONConverter using conv.
+func NewJSONConverter(conv JSONConvention) *JSONConverter {
+	return &JSONConverter{
+		conv: conv,
+	}
+}
+
+// SetRoot sets the name of the root element created by ToXML using
+// the JSONParker convention, which doesn't keep the root element.
+func (c *JSONConverter) SetRoot(name string) {
+	c.root = name
+}
+
+func (c *JSONConverter) textKey() string {
+	if c.conv == JSONBadgerFish {
+		return "$"
+	}
+	return "#text"
+}
+
+// ToJSON writes to w the JSON representation of the document read by r.
+func (c *JSONConverter) ToJSON(w io.Writer, r *Reader) (err error) {
+	c.out = c.out[:0]
+	c.frames = c.frames[:0]
+	c.holds = 0
+
+	top := c.push()
+	if c.conv != JSONParker {
+		c.open(top)
+	}
+
+	for err == nil && r.Next() {
+		switch e := r.Element().(type) {
+		case *StartElement:
+			c.start(e)
+			if e.HasEnd() {
+				c.end()
+			}
+		case *EndElement:
+			if len(c.frames) > 1 {
+				c.end()
+			}
+		case *TextElement:
+			if len(c.frames) > 1 {
+				f := &c.frames[len(c.frames)-1]
+				f.text = Unescape(f.text, []byte(e.String()))
+			}
+		}
+
+		if c.holds > 0 && len(c.out) >= jsonHoldLimit {
+			c.release()
+		}
+		if c.holds == 0 && len(c.out) >= jsonFlushSize {
+			_, err = w.Write(c.out)
+			c.out = c.out[:0]
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err = r.Error(); err != io.EOF {
+		return err
+	}
+
+	for len(c.frames) > 1 { // elements not closed
+		c.end()
+	}
+	top = &c.frames[0]
+	if c.conv == JSONParker {
+		if top.keys == 0 {
+			c.out = append(c.out, "null"...)
+		}
+	} else {
+		c.closeRun(top)
+		c.out = append(c.out, '}')
+	}
+
+	_, err = w.Write(c.out)
+	return err
+}
+
+func (c *JSONConverter) push() *jsonFrame {
+	n := len(c.frames)
+	if n < cap(c.frames) {
+		c.frames = c.frames[:n+1]
+	} else {
+		c.frames = append(c.frames, jsonFrame{})
+	}
+
+	f := &c.frames[n]
+	*f = jsonFrame{
+		last: f.last[:0],
+		text: f.text[:0],
+		hold: -1,
+	}
+	return f
+}
+
+// open writes the start of the object of f.
+func (c *JSONConverter) open(f *jsonFrame) {
+	if !f.open {
+		f.open = true
+		c.out = append(c.out, '{')
+	}
+}
+
+// key writes the key of the next value of f.
+func (c *JSONConverter) key(f *jsonFrame, prefix string, name []byte) {
+	if f.keys > 0 {
+		c.out = append(c.out, ',')
+	}
+	f.keys++
+
+	c.buf = append(append(c.buf[:0], prefix...), name...)
+	c.out = appendJSONString(c.out, c.buf)
+	c.out = append(c.out, ':')
+}
+
+// release stops holding the output, writing the held elements as single values.
+func (c *JSONConverter) release() {
+	for i := range c.frames {
+		c.frames[i].hold = -1
+	}
+	c.holds = 0
+}
+
+// closeRun ends the run of children of f with the same name.
+func (c *JSONConverter) closeRun(f *jsonFrame) {
+	if f.array {
+		f.array = false
+		c.out = append(c.out, ']')
+	}
+	if f.hold >= 0 {
+		f.hold = -1
+		c.holds--
+	}
+}
+
+func (c *JSONConverter) start(e *StartElement) {
+	c.push()
+	f := &c.frames[len(c.frames)-1]
+	p := &c.frames[len(c.frames)-2]
+
+	switch {
+	case p.skip:
+		f.skip = true
+		return
+	case c.conv == JSONParker && len(c.frames) == 2: // the root element is dropped
+		if p.keys > 0 {
+			f.skip = true
+			return
+		}
+		p.keys++
+	case p.array && bytes.Equal(p.last, e.name):
+		c.out = append(c.out, ',')
+	case p.hold >= 0 && bytes.Equal(p.last, e.name): // the run is an array
+		c.out = append(c.out, 0)
+		copy(c.out[p.hold+1:], c.out[p.hold:])
+		c.out[p.hold] = '['
+		c.out = append(c.out, ',')
+		p.array = true
+		p.hold = -1
+		c.holds--
+	default:
+		c.open(p)
+		c.closeRun(p)
+		c.key(p, "", e.name)
+		p.last = append(p.last[:0], e.name...)
+		if len(c.frames) > 2 { // the root element doesn't start an array
+			p.hold = len(c.out)
+			c.holds++
+		}
+	}
+
+	if c.conv == JSONParker || (c.conv == JSONAttrText && len(e.attrs) == 0) {
+		return
+	}
+
+	c.open(f)
+	e.attrs.Range(func(kv *KV) {
+		c.key(f, "@", kv.k)
+		f.text = Unescape(f.text[:0], kv.v)
+		c.out = appendJSONString(c.out, f.text)
+	})
+	f.text = f.text[:0]
+}
+
+func (c *JSONConverter) end() {
+	f := &c.frames[len(c.frames)-1]
+	if !f.skip {
+		c.closeRun(f)
+
+		switch {
+		case f.open:
+			// Parker drops the text of elements with children
+			if len(f.text) > 0 && c.conv != JSONParker {
+				c.key(f, "", []byte(c.textKey()))
+				c.out = appendJSONString(c.out, f.text)
+			}
+			c.out = append(c.out, '}')
+		case len(f.text) > 0:
+			c.out = appendJSONString(c.out, f.text)
+		default:
+			c.out = append(c.out, "null"...)
+		}
+	}
+
+	c.frames = c.frames[:len(c.frames)-1]
+}
+
+// ToXML writes to w the XML representation of the JSON read by d.
+//
+// The numbers are written as they are in the input (see json.Decoder.UseNumber).
+func (c *JSONConverter) ToXML(w *Writer, d *json.Decoder) error {
+	d.UseNumber()
+
+	if c.conv == JSONParker {
+		if c.root == "" {
+			return fmt.Errorf("xml: the Parker convention needs a root name (see JSONConverter.SetRoot)")
+		}
+		return c.value(w, d, c.root)
+	}
+
+	if err := expectDelim(d, '{'); err != nil {
+		return err
+	}
+	for d.More() {
+		name, err := c.nextKey(d)
+		if err != nil {
+			return err
+		}
+		if err = c.value(w, d, name); err != nil {
+			return err
+		}
+	}
+	return expectDelim(d, '}')
+}
+
+func expectDelim(d *json.Decoder, delim json.Delim) error {
+	t, err := d.Token()
+	if err == nil && t != delim {
+		err = fmt.Errorf("xml: expected %s in JSON. Got %v", delim, t)
+	}
+	return err
+}
+
+func (c *JSONConverter) nextKey(d *json.Decoder) (string, error) {
+	t, err := d.Token()
+	if err != nil {
+		return "", err
+	}
+	key, _ := t.(string)
+	return key, nil
+}
+
+// scalar appends to dst the escaped text of the scalar token t.
+func scalar(dst []byte, t json.Token, attr bool) ([]byte, bool) {
+	var s string
+	switch t := t.(type) {
+	case string:
+		s = t
+	case json.Number:
+		s = t.String()
+	case bool:
+		s = strconv.FormatBool(t)
+	case float64:
+		s = strconv.FormatFloat(t, 'g', -1, 64)
+	case nil:
+	default:
+		return dst, false
+	}
+	return escape(dst, []byte(s), attr), true
+}
+
+// value writes the JSON value as elements called name.
+func (c *JSONConverter) value(w *Writer, d *json.Decoder, name string) error {
+	if !isName(name) {
+		return fmt.Errorf("xml: invalid element name %q", name)
+	}
+
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case json.Delim('['):
+		for d.More() {
+			if err = c.value(w, d, name); err != nil {
+				return err
+			}
+		}
+		return expectDelim(d, ']')
+	case json.Delim('{'):
+		return c.object(w, d, name)
+	case nil:
+		c.buf = append(append(append(c.buf[:0], '<'), name...), "/>"...)
+		return w.writeBytes(c.buf)
+	}
+
+	c.buf = append(append(append(c.buf[:0], '<'), name...), '>')
+	c.buf, _ = scalar(c.buf, t, false)
+	c.buf = append(append(append(c.buf, "</"...), name...), '>')
+	return w.writeBytes(c.buf)
+}
+
+// object writes the element name with the content of a JSON object.
+func (c *JSONConverter) object(w *Writer, d *json.Decoder, name string) error {
+	c.buf = append(append(c.buf[:0], '<'), name...)
+	started := false
+
+	for d.More() {
+		key, err := c.nextKey(d)
+		if err != nil {
+			return err
+		}
+
+		if c.conv != JSONParker && len(key) > 0 && key[0] == '@' {
+			if !isName(key[1:]) {
+				return fmt.Errorf("xml: invalid attribute name %q of %s", key, name)
+			}
+			if started {
+				return fmt.Errorf("xml: attribute %s after the children of %s", key, name)
+			}
+			t, err := d.Token()
+			if err != nil {
+				return err
+			}
+			c.buf = append(append(append(c.buf, ' '), key[1:]...), `="`...)
+			var ok bool
+			if c.buf, ok = scalar(c.buf, t, true); !ok {
+				return fmt.Errorf("xml: attribute %s of %s is not a scalar", key, name)
+			}
+			c.buf = append(c.buf, '"')
+			continue
+		}
+
+		if !started {
+			started = true
+			if err = w.writeBytes(append(c.buf, '>')); err != nil {
+				return err
+			}
+		}
+
+		if c.conv != JSONParker && key == c.textKey() {
+			t, err := d.Token()
+			if err != nil {
+				return err
+			}
+			var ok bool
+			if c.buf, ok = scalar(c.buf[:0], t, false); !ok {
+				return fmt.Errorf("xml: text of %s is not a scalar", name)
+			}
+			err = w.writeBytes(c.buf)
+		} else {
+			err = c.value(w, d, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := expectDelim(d, '}'); err != nil {
+		return err
+	}
+
+	if !started {
+		return w.writeBytes(append(c.buf, "/>"...))
+	}
+	c.buf = append(append(append(c.buf[:0], "</"...), name...), '>')
+	return w.writeBytes(c.buf)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s to dst as a JSON string.
+func appendJSONString(dst, s []byte) []byte {
+	dst = append(dst, '"')
+	last := 0
+	for i, c := range s {
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		dst = append(dst, s[last:i]...)
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, `\n`...)
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\t':
+			dst = append(dst, `\t`...)
+		default:
+			dst = append(dst, `\u00`...)
+			dst = append(dst, hexDigits[c>>4], hexDigits[c&0xf])
+		}
+		last = i + 1
+	}
+	dst = append(dst, s[last:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,193 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const jsonDoc = `<?xml version="1.0"?>
+<feed lang="en">
+	<title>News &amp; more</title>
+	<entry id="1"><title>First</title><tag>a</tag><tag>b</tag></entry>
+	<entry id="2"><title>Second</title><empty/></entry>
+	<note>mixed <b>bold</b></note>
+</feed>`
+
+func TestJSONConverterToJSON(t *testing.T) {
+	for _, tc := range []struct {
+		conv     JSONConvention
+		expected string
+	}{
+		{
+			JSONAttrText,
+			`{"feed":{"@lang":"en","title":"News & more","entry":[` +
+				`{"@id":"1","title":"First","tag":["a","b"]},` +
+				`{"@id":"2","title":"Second","empty":null}],` +
+				`"note":{"b":"bold","#text":"mixed "}}}`,
+		},
+		{
+			JSONBadgerFish,
+			`{"feed":{"@lang":"en","title":{"$":"News & more"},"entry":[` +
+				`{"@id":"1","title":{"$":"First"},"tag":[{"$":"a"},{"$":"b"}]},` +
+				`{"@id":"2","title":{"$":"Second"},"empty":{}}],` +
+				`"note":{"b":{"$":"bold"},"$":"mixed "}}}`,
+		},
+		{
+			JSONParker,
+			`{"title":"News & more","entry":[` +
+				`{"title":"First","tag":["a","b"]},` +
+				`{"title":"Second","empty":null}],` +
+				`"note":{"b":"bold"}}`,
+		},
+	} {
+		var b bytes.Buffer
+		err := NewJSONConverter(tc.conv).ToJSON(&b, NewReader(strings.NewReader(jsonDoc)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tc.expected {
+			t.Fatalf("convention %d:\n%s\nexpected:\n%s", tc.conv, b.String(), tc.expected)
+		}
+		if !json.Valid(b.Bytes()) {
+			t.Fatalf("convention %d: invalid JSON %s", tc.conv, b.String())
+		}
+	}
+}
+
+// countingWriter records the size of the biggest write.
+type countingWriter struct {
+	n, max int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += len(b)
+	if len(b) > w.max {
+		w.max = len(b)
+	}
+	return len(b), nil
+}
+
+func TestJSONConverterStreams(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<rows>")
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&b, `<row n="%d">value "%d"</row>`, i, i)
+	}
+	b.WriteString("</rows>")
+
+	var w countingWriter
+	err := NewJSONConverter(JSONAttrText).ToJSON(&w, NewReader(strings.NewReader(b.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.max > 2*jsonFlushSize {
+		t.Fatalf("the output has been held: %d bytes written at once", w.max)
+	}
+}
+
+func TestJSONConverterStreamsWrapped(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<root><rows>")
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&b, `<row n="%d">value "%d"</row>`, i, i)
+	}
+	b.WriteString("</rows></root>")
+
+	var w countingWriter
+	var out bytes.Buffer
+	err := NewJSONConverter(JSONAttrText).ToJSON(io.MultiWriter(&w, &out), NewReader(strings.NewReader(b.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.max > jsonHoldLimit+2*jsonFlushSize || w.max == w.n {
+		t.Fatalf("the output has been held: %d of %d bytes written at once", w.max, w.n)
+	}
+	if !json.Valid(out.Bytes()) || !strings.HasPrefix(out.String(), `{"root":{"rows":{"row":[{"@n":"0"`) {
+		t.Fatalf("unexpected JSON %.100s", out.String())
+	}
+}
+
+func TestJSONConverterToXML(t *testing.T) {
+	for _, tc := range []struct {
+		conv     JSONConvention
+		json     string
+		expected string
+	}{
+		{
+			JSONAttrText,
+			`{"feed":{"@lang":"en","title":"News & more","entry":[{"@id":1,"tag":["a","b"]},{"#text":"x<y"}],"empty":null}}`,
+			`<feed lang="en"><title>News &amp; more</title><entry id="1"><tag>a</tag><tag>b</tag></entry><entry>x&lt;y</entry><empty/></feed>`,
+		},
+		{
+			JSONBadgerFish,
+			`{"feed":{"@lang":"en","title":{"$":"News"},"n":[{"$":1.50},{"$":true}],"empty":{}}}`,
+			`<feed lang="en"><title>News</title><n>1.50</n><n>true</n><empty/></feed>`,
+		},
+		{
+			JSONParker,
+			`{"title":"News","entry":[{"title":"First"},{"title":"Second"}]}`,
+			`<feed><title>News</title><entry><title>First</title></entry><entry><title>Second</title></entry></feed>`,
+		},
+	} {
+		var b bytes.Buffer
+		c := NewJSONConverter(tc.conv)
+		c.SetRoot("feed")
+		if err := c.ToXML(NewWriter(&b), json.NewDecoder(strings.NewReader(tc.json))); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tc.expected {
+			t.Fatalf("convention %d:\n%s\nexpected:\n%s", tc.conv, b.String(), tc.expected)
+		}
+	}
+}
+
+func TestJSONConverterInvalidNames(t *testing.T) {
+	for _, js := range []string{
+		`{"a":{"@x\"><evil y=\"":"1"}}`,
+		`{"a":{"b><admin/><c":"v"}}`,
+		`{"a":{"@":"v"}}`,
+		`{"1a":null}`,
+		`{"":"v"}`,
+	} {
+		var b bytes.Buffer
+		err := NewJSONConverter(JSONAttrText).ToXML(NewWriter(&b), json.NewDecoder(strings.NewReader(js)))
+		if err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Fatalf("%s: expected invalid name error. Got %v", js, err)
+		}
+		if strings.Contains(b.String(), "evil") || strings.Contains(b.String(), "admin") {
+			t.Fatalf("%s: markup injected: %s", js, b.String())
+		}
+	}
+
+	for _, name := range []string{"a", "_x.y-1", "ns:el", "caf\u00e9", "名前"} {
+		js := `{"` + name + `":null}`
+		if err := NewJSONConverter(JSONAttrText).ToXML(NewWriter(io.Discard), json.NewDecoder(strings.NewReader(js))); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestJSONConverterRoundTrip(t *testing.T) {
+	c := NewJSONConverter(JSONAttrText)
+
+	var js bytes.Buffer
+	if err := c.ToJSON(&js, NewReader(strings.NewReader(jsonDoc))); err != nil {
+		t.Fatal(err)
+	}
+	var x bytes.Buffer
+	if err := c.ToXML(NewWriter(&x), json.NewDecoder(&js)); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `<feed lang="en"><title>News &amp; more</title>` +
+		`<entry id="1"><title>First</title><tag>a</tag><tag>b</tag></entry>` +
+		`<entry id="2"><title>Second</title><empty/></entry>` +
+		`<note><b>bold</b>mixed </note></feed>`
+	if x.String() != expected {
+		t.Fatalf("\n%s\nexpected:\n%s", x.String(), expected)
+	}
+}
@@ -0,0 +1,106 @@
+package xml
+
+import "io"
+
+// Kind identifies the type of an Element.
+type Kind uint8
+
+const (
+	// NoKind is returned by Reader.Kind when there is no element.
+	NoKind Kind = iota
+	StartKind
+	EndKind
+	TextKind
+
+	// CustomKind is the first Kind available to custom Element implementations.
+	CustomKind Kind = 64
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NoKind:
+		return "none"
+	case StartKind:
+		return "start"
+	case EndKind:
+		return "end"
+	case TextKind:
+		return "text"
+	}
+	return "custom"
+}
+
+// Kind returns the kind of the last element read.
+//
+// It allows dispatching the elements with a switch without type assertions
+// using Start, End and Text to access them.
+func (r *Reader) Kind() Kind {
+	if r.e == nil {
+		return NoKind
+	}
+	return r.e.Kind()
+}
+
+// Start returns the last element read if it is a StartElement, or nil.
+func (r *Reader) Start() *StartElement {
+	s, _ := r.e.(*StartElement)
+	return s
+}
+
+// End returns the last element read if it is an EndElement, or nil.
+func (r *Reader) End() *EndElement {
+	e, _ := r.e.(*EndElement)
+	return e
+}
+
+// Text returns the last element read if it is a TextElement, or nil.
+func (r *Reader) Text() *TextElement {
+	t, _ := r.e.(*TextElement)
+	return t
+}
+
+// ElementSource produces the elements read by a Reader created with NewSourceReader.
+type ElementSource interface {
+	// NextElement returns the next element, or io.EOF when there are no more.
+	NextElement() (Element, error)
+}
+
+// Elements is an ElementSource returning the elements of the slice in order.
+type Elements []Element
+
+// NextElement implements ElementSource.
+func (es *Elements) NextElement() (Element, error) {
+	if len(*es) == 0 {
+		return nil, io.EOF
+	}
+	e := (*es)[0]
+	*es = (*es)[1:]
+	return e, nil
+}
+
+// NewSourceReader returns a Reader reading the elements produced by src
+// instead of parsing a document. It allows feeding code using a Reader
+// from tests or adapters, including custom Element implementations.
+//
+// The elements are returned as they are produced: the settings
+// changing how a document is parsed don't apply, and the elements
+// are not returned to the pools when reading the next one.
+func NewSourceReader(src ElementSource) *Reader {
+	r := NewReader(nil)
+	r.src = src
+	return r
+}
+
+// nextFromSource reads the next element from r.src.
+func (r *Reader) nextFromSource() bool {
+	r.e, r.err = r.src.NextElement()
+	if r.err != nil {
+		r.e = nil
+	}
+
+	ok := r.e != nil
+	if r.progress != nil || ok {
+		r.report(ok)
+	}
+	return ok
+}
@@ -0,0 +1,64 @@
+package xml
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderKind(t *testing.T) {
+	r := NewReader(strings.NewReader(`<doc><a x="1">text</a></doc>`))
+	if r.Kind() != NoKind {
+		t.Fatalf("unexpected kind %s before reading", r.Kind())
+	}
+
+	var b strings.Builder
+	for r.Next() {
+		switch r.Kind() {
+		case StartKind:
+			b.WriteString("+" + r.Start().NameUnsafe())
+		case EndKind:
+			b.WriteString("-" + r.End().NameUnsafe())
+		case TextKind:
+			b.WriteString("'" + r.Text().String())
+		}
+		if r.Kind() != r.Element().Kind() {
+			t.Fatalf("kind mismatch for %s", r.Element())
+		}
+	}
+
+	if expected := "+doc+a'text-a-doc"; b.String() != expected {
+		t.Fatalf("got %s. Expected %s", b.String(), expected)
+	}
+	if r.Start() != nil || r.End() != nil || r.Text() != nil {
+		t.Fatal("expected no element at EOF")
+	}
+}
+
+type comment string
+
+func (c comment) Kind() Kind     { return CustomKind }
+func (c comment) String() string { return "<!--" + string(c) + "-->" }
+func (c comment) Raw() []byte    { return nil }
+
+func TestSourceReader(t *testing.T) {
+	a := NewStart("a", false, nil)
+	es := Elements{a, comment("note"), NewText("text"), NewEnd("a")}
+	r := NewSourceReader(&es)
+
+	var kinds []string
+	for r.Next() {
+		kinds = append(kinds, r.Kind().String()+":"+r.Element().String())
+	}
+	if err := r.Error(); err != io.EOF {
+		t.Fatal(err)
+	}
+
+	expected := "start:<a>|custom:<!--note-->|text:text|end:</a>"
+	if strings.Join(kinds, "|") != expected {
+		t.Fatalf("got %q", kinds)
+	}
+	if a.NameUnsafe() != "a" {
+		t.Fatal("source element has been released")
+	}
+}
@@ -1,16 +1,24 @@
 package xml
 
 import (
-	"bufio"
+	"strconv"
+	"time"
 )
 
 // KV represents an attr which is a key-value pair.
 type KV struct {
 	k, v []byte
+	ik   string // interned key (see Reader.SetNames).
+	gen  uint32 // generation of the list, kept in its first attr (see Attrs.touch).
 }
 
 // Key returns the key.
+//
+// The key is not allocated if it has been interned by the Reader (see Reader.SetNames).
 func (kv *KV) Key() string {
+	if kv.ik == b2s(kv.k) {
+		return kv.ik
+	}
 	return string(kv.k)
 }
 
@@ -43,36 +51,64 @@ func (kv *KV) ValueUnsafe() string {
 	return b2s(kv.v)
 }
 
+// Int parses the value as a base 10 integer.
+func (kv *KV) Int() (int64, error) {
+	return strconv.ParseInt(b2s(trimWS(kv.v)), 10, 64)
+}
+
+// Uint parses the value as a base 10 unsigned integer.
+func (kv *KV) Uint() (uint64, error) {
+	return strconv.ParseUint(b2s(trimWS(kv.v)), 10, 64)
+}
+
+// Float parses the value as a floating point number.
+func (kv *KV) Float() (float64, error) {
+	return strconv.ParseFloat(b2s(trimWS(kv.v)), 64)
+}
+
+// Bool parses the value as a boolean.
+//
+// Valid values are 1, 0, true and false.
+func (kv *KV) Bool() (bool, error) {
+	return parseBool(b2s(trimWS(kv.v)))
+}
+
+// Duration parses the value as a time.Duration (see time.ParseDuration).
+func (kv *KV) Duration() (time.Duration, error) {
+	return time.ParseDuration(b2s(trimWS(kv.v)))
+}
+
+// Time parses the value as a time.Time formatted with layout.
+func (kv *KV) Time(layout string) (time.Time, error) {
+	return parseTime(layout, b2s(trimWS(kv.v)))
+}
+
 func (kv *KV) reset() {
 	kv.k = kv.k[:0]
 	kv.v = kv.v[:0]
+	kv.ik = ""
 }
 
-func (kv *KV) parse(r *bufio.Reader) error {
+func (kv *KV) parse(r *scanner) error {
 	k, err := r.ReadBytes('=')
 	if err == nil {
-		n := len(k) - 2
-		for k[n] == ' ' {
-			n--
-		}
-
-		kv.k = append(kv.k[:0], k[:n+1]...)
+		kv.k = append(kv.k[:0], trimWS(k[:len(k)-1])...)
 		var (
 			c byte
 			v []byte
 		)
 	loop:
 		for {
-			c, err = skipWS(r)
+			c, err = r.skipWS()
 			if err != nil {
 				break
 			}
 
 			switch c {
-			case '"':
-				v, err = r.ReadBytes('"')
+			case '"', '\'':
+				v, err = r.ReadBytes(c)
 				if err == nil {
-					kv.v = append(kv.v[:0], v[:len(v)-1]...)
+					kv.v = normalizeAttr(append(kv.v[:0], v[:len(v)-1]...))
 				}
 				break loop
 			}
@@ -0,0 +1,73 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKVTyped(t *testing.T) {
+	const xmlStr = `<row r="12" s="-3" ht="15.75" customHeight="1" hidden="false" d="1m30s" t="2020-05-01"/>`
+
+	r := NewReader(strings.NewReader(xmlStr))
+	if !r.Next() {
+		t.Fatal(r.Error())
+	}
+	attrs := r.Element().(*StartElement).Attrs()
+
+	if n, err := attrs.Get("r").Uint(); err != nil || n != 12 {
+		t.Fatalf("Unexpected r: %d (%v)", n, err)
+	}
+	if n, err := attrs.Get("s").Int(); err != nil || n != -3 {
+		t.Fatalf("Unexpected s: %d (%v)", n, err)
+	}
+	if f, err := attrs.Get("ht").Float(); err != nil || f != 15.75 {
+		t.Fatalf("Unexpected ht: %f (%v)", f, err)
+	}
+	if b, err := attrs.Get("customHeight").Bool(); err != nil || !b {
+		t.Fatalf("Unexpected customHeight: %v (%v)", b, err)
+	}
+	if b, err := attrs.Get("hidden").Bool(); err != nil || b {
+		t.Fatalf("Unexpected hidden: %v (%v)", b, err)
+	}
+	if d, err := attrs.Get("d").Duration(); err != nil || d != 90*time.Second {
+		t.Fatalf("Unexpected d: %s (%v)", d, err)
+	}
+	if tm, err := attrs.Get("t").Time("2006-01-02"); err != nil || tm.Day() != 1 {
+		t.Fatalf("Unexpected t: %s (%v)", tm, err)
+	}
+	if _, err := attrs.Get("ht").Int(); err == nil {
+		t.Fatal("Expected error parsing a float as int")
+	}
+
+	if n := attrs.GetInt("r", 0); n != 12 {
+		t.Fatalf("Unexpected GetInt: %d", n)
+	}
+	if n := attrs.GetInt("missing", 7); n != 7 {
+		t.Fatalf("Unexpected GetInt default: %d", n)
+	}
+	if b := attrs.GetBool("ht", true); !b {
+		t.Fatal("Expected GetBool default on invalid value")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		attrs.GetInt("r", 0)
+		attrs.GetFloat("ht", 0)
+		attrs.GetBool("customHeight", false)
+	})
+	if allocs != 0 {
+		t.Fatalf("Expected no allocations. Got %f", allocs)
+	}
+}
+
+func TestTextTyped(t *testing.T) {
+	if n, err := NewText(" 42\n").Int(); err != nil || n != 42 {
+		t.Fatalf("Unexpected int: %d (%v)", n, err)
+	}
+	if b, err := NewText("true").Bool(); err != nil || !b {
+		t.Fatalf("Unexpected bool: %v (%v)", b, err)
+	}
+	if _, err := NewText("yes").Bool(); err == nil {
+		t.Fatal("Expected error parsing yes as bool")
+	}
+}
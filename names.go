@@ -0,0 +1,119 @@
+package xml
+
+// Name is the handle of a name interned in a Names table.
+//
+// The zero Name is not a valid handle.
+type Name uint32
+
+// defaultNamesLimit is the default maximum number of names in a Names table.
+const defaultNamesLimit = 4096
+
+// Names is a table of interned element and attribute names.
+//
+// Documents use a few distinct names many times, so a Reader using
+// a Names table (see Reader.SetNames) returns the same strings
+// for the names instead of allocating them for every element.
+//
+// A Names table is not safe for concurrent use, but it can be shared
+// by the readers used from the same goroutine.
+type Names struct {
+	ids   map[string]Name
+	names []string
+	limit int
+}
+
+// NewNames creates an empty Names table.
+func NewNames() *Names {
+	return &Names{
+		ids:   make(map[string]Name),
+		names: []string{""},
+		limit: defaultNamesLimit,
+	}
+}
+
+// SetLimit sets the maximum number of names interned by the readers.
+//
+// Once the limit is reached the new names are allocated as usual,
+// so documents with many distinct names don't make the table grow forever.
+// The default limit is 4096. Register is not affected by the limit.
+func (t *Names) SetLimit(n int) {
+	t.limit = n
+}
+
+// Register interns name returning its handle.
+//
+// Registering the names before reading allows comparing the
+// handles returned by StartElement.NameID and EndElement.NameID.
+func (t *Names) Register(name string) Name {
+	if id, ok := t.ids[name]; ok {
+		return id
+	}
+	return t.add(name)
+}
+
+// Lookup returns the handle of name, or zero if it hasn't been interned.
+func (t *Names) Lookup(name []byte) Name {
+	return t.ids[b2s(name)]
+}
+
+// String returns the name of the handle n.
+func (t *Names) String(n Name) string {
+	if int(n) >= len(t.names) {
+		return ""
+	}
+	return t.names[n]
+}
+
+// Len returns the number of names in the table.
+func (t *Names) Len() int {
+	return len(t.names) - 1
+}
+
+func (t *Names) add(name string) Name {
+	id := Name(len(t.names))
+	t.names = append(t.names, name)
+	t.ids[name] = id
+	return id
+}
+
+// intern returns the handle and the interned string of name.
+//
+// If name is not in the table and the table is full it returns zero.
+func (t *Names) intern(name []byte) (Name, string) {
+	if id, ok := t.ids[b2s(name)]; ok {
+		return id, t.names[id]
+	}
+	if len(name) == 0 || len(t.names) > t.limit {
+		return 0, ""
+	}
+	id := t.add(string(name))
+	return id, t.names[id]
+}
+
+// SetNames makes the reader intern the names of the elements
+// and attributes in t, so StartElement.Name, EndElement.Name and KV.Key
+// return strings that are safe to retain without allocating them.
+//
+// A nil table disables the interning.
+func (r *Reader) SetNames(t *Names) {
+	r.names = t
+}
+
+// Names returns the Names table used by the reader, or nil.
+func (r *Reader) Names() *Names {
+	return r.names
+}
+
+// intern interns the names of e.
+func (r *Reader) intern(e Element) {
+	switch e := e.(type) {
+	case *StartElement:
+		e.id, e.iname = r.names.intern(e.name)
+		for i := range e.attrs {
+			kv := &e.attrs[i]
+			_, kv.ik = r.names.intern(kv.k)
+		}
+	case *EndElement:
+		e.id, e.iname = r.names.intern(e.name)
+	}
+}
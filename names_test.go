@@ -0,0 +1,84 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReaderNames(t *testing.T) {
+	const str = `<doc><row n="1"/><row n="2"></row><other n="3"/></doc>`
+
+	names := NewNames()
+	row := names.Register("row")
+
+	r := NewReader(strings.NewReader(str))
+	r.SetNames(names)
+
+	var rows, ends int
+	var retained []string
+	for r.Next() {
+		switch r.Kind() {
+		case StartKind:
+			s := r.Start()
+			if s.NameID() == row {
+				rows++
+			}
+			retained = append(retained, s.Name())
+			if kv := s.Attrs().Get("n"); kv != nil {
+				retained = append(retained, kv.Key())
+			}
+		case EndKind:
+			if r.End().NameID() == row {
+				ends++
+			}
+		}
+	}
+
+	if rows != 2 || ends != 1 {
+		t.Fatalf("expected 2 rows and 1 end. Got %d and %d", rows, ends)
+	}
+	expected := "doc,row,n,row,n,other,n"
+	if got := strings.Join(retained, ","); got != expected {
+		t.Fatalf("got %s. Expected %s", got, expected)
+	}
+	if names.Len() != 4 || names.String(names.Lookup([]byte("other"))) != "other" {
+		t.Fatalf("unexpected table of %d names", names.Len())
+	}
+}
+
+func TestReaderNamesAllocs(t *testing.T) {
+	const str = `<row n="1"/>`
+
+	names := NewNames()
+	r := NewReader(strings.NewReader(str))
+	r.SetNames(names)
+	r.Next()
+	s := r.Start()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if s.Name() != "row" || s.Attrs().Get("n").Key() != "n" {
+			t.Fatal("unexpected names")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations. Got %v", allocs)
+	}
+
+	s.SetName("col")
+	if s.Name() != "col" || s.NameID() != 0 {
+		t.Fatal("the interned name has not been invalidated")
+	}
+}
+
+func TestNamesLimit(t *testing.T) {
+	names := NewNames()
+	names.SetLimit(1)
+
+	r := NewReader(strings.NewReader(`<a><b/></a>`))
+	r.SetNames(names)
+	for r.Next() {
+	}
+	if names.Len() != 1 {
+		t.Fatalf("expected 1 name. Got %d", names.Len())
+	}
+}
@@ -0,0 +1,253 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+const (
+	defaultChunkSize = 4 << 20
+	// boundaryWindow is the number of bytes read at once looking for a boundary.
+	boundaryWindow = 64 << 10
+)
+
+// ParallelReader parses a document in chunks on multiple goroutines.
+//
+// The document is split before the start tags of the split element,
+// so every chunk holds a sequence of sibling elements, like the rows of a sheet.
+// The first chunk also holds everything before the first split element,
+// and the last one everything after the last.
+//
+// The split element must not nest and its start tag must not appear
+// inside comments, CDATA sections or attribute values. The DTD of the
+// document is only known by the Reader of the first chunk.
+type ParallelReader struct {
+	r         io.ReaderAt
+	size      int64
+	split     []byte
+	workers   int
+	chunkSize int64
+}
+
+// Chunk is a part of the document parsed by a ParallelReader.
+type Chunk struct {
+	// Index is the position of the chunk in the document, starting at 0.
+	Index int
+	// Offset is the position of the chunk in the input.
+	// Reader.Offset is relative to it.
+	Offset int64
+	// Len is the number of bytes of the chunk.
+	Len int64
+	// Reader reads the chunk.
+	Reader *Reader
+}
+
+// ChunkFunc parses a chunk returning the result to be emitted.
+//
+// ChunkFunc is called concurrently from multiple goroutines.
+// The Elements read are released once the next one is read,
+// so the result must not reference them.
+type ChunkFunc func(c *Chunk) (interface{}, error)
+
+// EmitFunc receives the results of the chunks in document order.
+type EmitFunc func(index int, result interface{}) error
+
+// NewParallelReader creates a ParallelReader that parses data
+// splitting it before the split elements.
+func NewParallelReader(data []byte, split string) *ParallelReader {
+	return NewParallelReaderAt(bytes.NewReader(data), int64(len(data)), split)
+}
+
+// NewParallelReaderAt creates a ParallelReader that parses the size bytes
+// of r splitting them before the split elements.
+func NewParallelReaderAt(r io.ReaderAt, size int64, split string) *ParallelReader {
+	return &ParallelReader{
+		r:         r,
+		size:      size,
+		split:     append([]byte{'<'}, split...),
+		workers:   runtime.GOMAXPROCS(0),
+		chunkSize: defaultChunkSize,
+	}
+}
+
+// SetWorkers sets the number of goroutines parsing chunks.
+//
+// The default is GOMAXPROCS.
+func (p *ParallelReader) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.workers = n
+}
+
+// SetChunkSize sets the approximate size of the chunks in bytes.
+//
+// The default is 4MiB.
+func (p *ParallelReader) SetChunkSize(n int64) {
+	if n < 1 {
+		n = 1
+	}
+	p.chunkSize = n
+}
+
+type chunkResult struct {
+	index  int
+	result interface{}
+	err    error
+}
+
+// Run parses the chunks with parse and passes their results to emit in document order.
+//
+// Every chunk is parsed by its own Reader and the elements come from the
+// pools of the goroutine parsing it. Run stops at the first error returned
+// by parse or emit, or by the splitting of the input, returning it.
+func (p *ParallelReader) Run(parse ChunkFunc, emit EmitFunc) error {
+	bounds, err := p.bounds()
+	if err != nil {
+		return err
+	}
+
+	var (
+		jobs    = make(chan int)
+		results = make(chan chunkResult, p.workers)
+		// tokens limits the chunks parsed ahead of the emitted ones.
+		tokens = make(chan struct{}, 2*p.workers)
+		done   = make(chan struct{})
+		wg     sync.WaitGroup
+	)
+
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := chunkResult{index: i}
+				res.result, res.err = parse(p.chunk(i, bounds))
+				select {
+				case results <- res:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := 0; i < len(bounds)-1; i++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]chunkResult)
+	next := 0
+	for res := range results {
+		pending[res.index] = res
+		for err == nil {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+
+			err = res.err
+			if err == nil {
+				err = emit(res.index, res.result)
+			}
+			next++
+			<-tokens
+		}
+		if err != nil {
+			close(done)
+			for range results {
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunk creates the Chunk i.
+func (p *ParallelReader) chunk(i int, bounds []int64) *Chunk {
+	off, n := bounds[i], bounds[i+1]-bounds[i]
+	return &Chunk{
+		Index:  i,
+		Offset: off,
+		Len:    n,
+		Reader: NewReader(io.NewSectionReader(p.r, off, n)),
+	}
+}
+
+// bounds returns the offsets where the chunks start followed by the input size.
+func (p *ParallelReader) bounds() ([]int64, error) {
+	bounds := []int64{0}
+	for off := p.chunkSize; off < p.size; {
+		b, err := p.boundary(off)
+		if err != nil {
+			return nil, err
+		}
+		if b < 0 {
+			break
+		}
+		if b > bounds[len(bounds)-1] {
+			bounds = append(bounds, b)
+		}
+		off = b + p.chunkSize
+	}
+	return append(bounds, p.size), nil
+}
+
+// boundary returns the offset of the first start tag of the split element
+// found at or after off, or -1 if there's none.
+func (p *ParallelReader) boundary(off int64) (int64, error) {
+	buf := make([]byte, boundaryWindow+len(p.split))
+	for off < p.size {
+		n, err := p.r.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return -1, err
+		}
+		b := buf[:n]
+
+		for i := 0; ; {
+			j := bytes.Index(b[i:], p.split)
+			if j < 0 {
+				break
+			}
+			i += j + len(p.split)
+			if i == len(b) {
+				if off+int64(i) == p.size {
+					return -1, nil
+				}
+				break // the next window tells
+			}
+			switch b[i] {
+			case ' ', '\t', '\r', '\n', '>', '/':
+				return off + int64(i-len(p.split)), nil
+			}
+		}
+
+		if n < len(p.split)+1 {
+			break
+		}
+		// overlap the windows so tags between them are found
+		off += int64(n - len(p.split))
+	}
+	return -1, nil
+}
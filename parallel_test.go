@@ -0,0 +1,159 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func makeSheet(rows int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0"?><sheet><rows>`)
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&b, "\n\t<row n=\"%d\"><c>%d</c><c>cell</c></row>", i, i)
+	}
+	b.WriteString("\n</rows></sheet>\n")
+	return b.Bytes()
+}
+
+func rowNumbers(c *Chunk) (interface{}, error) {
+	var ns []string
+	r := c.Reader
+	for r.Next() {
+		if e, ok := r.Element().(*StartElement); ok && e.NameUnsafe() == "row" {
+			ns = append(ns, e.Attrs().Get("n").Value())
+		}
+	}
+	if err := r.Error(); err != io.EOF {
+		return nil, err
+	}
+	return ns, nil
+}
+
+func TestParallelReader(t *testing.T) {
+	const rows = 1000
+	data := makeSheet(rows)
+
+	for _, size := range []int64{1, 100, 4096, 1 << 20} {
+		p := NewParallelReader(data, "row")
+		p.SetWorkers(4)
+		p.SetChunkSize(size)
+
+		var got []string
+		next := 0
+		err := p.Run(rowNumbers, func(index int, result interface{}) error {
+			if index != next {
+				t.Fatalf("chunk %d emitted before %d", index, next)
+			}
+			next++
+			got = append(got, result.([]string)...)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != rows {
+			t.Fatalf("chunk size %d: expected %d rows. Got %d", size, rows, len(got))
+		}
+		for i, n := range got {
+			if n != fmt.Sprint(i) {
+				t.Fatalf("chunk size %d: row %d out of order: %s", size, i, n)
+			}
+		}
+	}
+}
+
+func TestParallelReaderBoundaries(t *testing.T) {
+	const str = `<rows><rowset/><row>1</row><row >2</row><row/></rows>`
+
+	p := NewParallelReaderAt(strings.NewReader(str), int64(len(str)), "row")
+	p.SetChunkSize(1)
+
+	var chunks []string
+	err := p.Run(func(c *Chunk) (interface{}, error) {
+		return str[c.Offset : c.Offset+c.Len], nil
+	}, func(index int, result interface{}) error {
+		chunks = append(chunks, result.(string))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"<rows><rowset/>", "<row>1</row>", "<row >2</row>", "<row/></rows>"}
+	if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected chunks %q", chunks)
+	}
+}
+
+func TestParallelReaderError(t *testing.T) {
+	p := NewParallelReader(makeSheet(100), "row")
+	p.SetChunkSize(64)
+
+	errChunk := errors.New("chunk")
+	emitted := 0
+	err := p.Run(func(c *Chunk) (interface{}, error) {
+		if c.Index == 3 {
+			return nil, errChunk
+		}
+		return nil, nil
+	}, func(index int, result interface{}) error {
+		emitted++
+		return nil
+	})
+	if err != errChunk {
+		t.Fatalf("expected chunk error. Got %v", err)
+	}
+	if emitted != 3 {
+		t.Fatalf("expected 3 chunks emitted. Got %d", emitted)
+	}
+}
+
+func countRows(r *Reader) (int, error) {
+	n := 0
+	for r.Next() {
+		if e, ok := r.Element().(*StartElement); ok && e.NameUnsafe() == "row" {
+			n++
+		}
+	}
+	if err := r.Error(); err != io.EOF {
+		return n, err
+	}
+	return n, nil
+}
+
+func BenchmarkReaderSheet(b *testing.B) {
+	data := makeSheet(100000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := countRows(NewReader(bytes.NewReader(data))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParallelReaderSheet(b *testing.B) {
+	data := makeSheet(100000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p := NewParallelReader(data, "row")
+		p.SetChunkSize(256 << 10)
+
+		err := p.Run(func(c *Chunk) (interface{}, error) {
+			return countRows(c.Reader)
+		}, func(index int, result interface{}) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrParserClosed is returned when feeding a closed Parser.
+var ErrParserClosed = errors.New("xml: parser closed")
+
+// ParserFunc receives the elements read by a Parser.
+//
+// The element is only valid until ParserFunc returns,
+// as the parser reuses it for the next element.
+type ParserFunc func(e Element) error
+
+// Parser is a push parser: the input is fed in fragments as they arrive
+// instead of being read from an io.Reader.
+//
+// Every element is passed to the ParserFunc as soon as it is complete.
+// The bytes of an incomplete element are kept until the next Feed,
+// which only parses them again once the sequence completing it has been fed.
+type Parser struct {
+	r   *Reader
+	fn  ParserFunc
+	err error
+
+	// the incomplete token starts start bytes after the buffered input,
+	// and it is completed by delim, which is not in the first scanned bytes.
+	start   int
+	scanned int
+	delim   string
+}
+
+// NewParser returns a Parser that passes the elements to fn.
+func NewParser(fn ParserFunc) *Parser {
+	r := NewReader(nil)
+	r.r.push = true
+	r.r.buf = r.r.buf[:0]
+
+	return &Parser{
+		r:  r,
+		fn: fn,
+	}
+}
+
+// Reader returns the Reader used by the parser, so it can be configured
+// (see Reader.SetWhitespace or Reader.SetKeepRaw) or queried from
+// the ParserFunc (see Reader.Offset).
+//
+// Calling Next on it breaks the parser.
+func (p *Parser) Reader() *Reader {
+	return p.r
+}
+
+// Feed parses b passing the complete elements to the ParserFunc.
+//
+// Feed returns the first error found parsing or returned by the ParserFunc,
+// after which the parser stops.
+func (p *Parser) Feed(b []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.r.r.feed(b)
+	if !p.complete() {
+		return nil
+	}
+	return p.run()
+}
+
+// complete reports whether the input fed may complete the pending token.
+func (p *Parser) complete() bool {
+	if p.delim == "" {
+		return true
+	}
+
+	s := p.r.r
+	b := s.buf[s.r+p.start : s.w]
+	from := p.scanned - len(p.delim) + 1
+	if from < 0 {
+		from = 0
+	}
+	p.scanned = len(b)
+	return indexFold(b[from:], p.delim) >= 0
+}
+
+// wait sets the sequence completing the token left incomplete
+// in the buffered input.
+func (p *Parser) wait(r int, n int64) {
+	s := p.r.r
+	p.delim = ""
+	if p.r.pos < n { // no token started
+		return
+	}
+
+	p.start = int(p.r.pos - n)
+	b := s.buf[r+p.start : s.w]
+	p.scanned = len(b)
+
+	switch {
+	case p.r.rawText:
+		p.delim = "</" + string(p.r.stack[len(p.r.stack)-1])
+	case len(trimWS(b)) == 0:
+	case trimWS(b)[0] != '<':
+		p.delim = "<"
+	default:
+		b = trimWS(b)
+		switch {
+		case bytes.HasPrefix(b, []byte("<!--")):
+			p.delim = "-->"
+		case bytes.HasPrefix(b, []byte("<![CDATA[")):
+			p.delim = "]]>"
+		case bytes.HasPrefix(b, []byte("<?")):
+			p.delim = "?>"
+		default:
+			p.delim = ">"
+		}
+	}
+}
+
+// Close parses the rest of the input, like the text after the last element.
+//
+// Close returns io.ErrUnexpectedEOF if the input ends inside a tag,
+// comment, CDATA section or processing instruction.
+func (p *Parser) Close() error {
+	if p.err != nil {
+		if p.err == ErrParserClosed {
+			return nil
+		}
+		return p.err
+	}
+
+	// text (and raw text) is complete at the end of the input
+	truncated := p.delim != "" && p.delim[0] != '<'
+	p.delim = ""
+	if truncated {
+		p.err = io.ErrUnexpectedEOF
+		return p.err
+	}
+
+	p.r.r.closed = true
+	if err := p.run(); err != nil {
+		return err
+	}
+	p.err = ErrParserClosed
+	return nil
+}
+
+func (p *Parser) run() error {
+	s := p.r.r
+	for {
+		r, n := s.r, s.n
+		if p.r.Next() {
+			if err := p.fn(p.r.e); err != nil {
+				p.err = err
+				return err
+			}
+			continue
+		}
+
+		switch p.r.err {
+		case errIncomplete: // roll back to the start of the element
+			p.wait(r, n)
+			s.r, s.n = r, n
+			s.raw = s.raw[:0]
+			p.r.err = nil
+			return nil
+		case io.EOF:
+			return nil
+		}
+		p.err = p.r.err
+		return p.err
+	}
+}
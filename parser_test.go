@@ -0,0 +1,194 @@
+package xml
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const parserDoc = `<?xml version="1.0"?>
+<!DOCTYPE doc [<!ENTITY e "entity">]>
+<doc xmlns="urn:doc">
+	<!-- comment -->
+	<item id="1" name='first'>text &e;</item>
+	<empty/><empty a="b" />
+</doc> trailing`
+
+// collect returns the elements passed to the parser in the format of readAll.
+func collect(b *strings.Builder) ParserFunc {
+	return func(e Element) error {
+		switch e := e.(type) {
+		case *StartElement:
+			b.WriteString("<" + e.Name())
+			e.Attrs().Range(func(kv *KV) {
+				b.WriteString(" " + kv.Key() + "=" + kv.Value())
+			})
+			if e.HasEnd() {
+				b.WriteString("/")
+			}
+			b.WriteString(">")
+		case *EndElement:
+			b.WriteString("</" + e.Name() + ">")
+		case *TextElement:
+			b.WriteString(e.String())
+		}
+		return nil
+	}
+}
+
+func TestParser(t *testing.T) {
+	expected := readAll(NewReader(strings.NewReader(parserDoc)))
+
+	// every split point
+	for i := 0; i <= len(parserDoc); i++ {
+		var b strings.Builder
+		p := NewParser(collect(&b))
+		if err := p.Feed([]byte(parserDoc[:i])); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Feed([]byte(parserDoc[i:])); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Fatalf("split at %d:\n%s\nexpected:\n%s", i, b.String(), expected)
+		}
+	}
+
+	// byte by byte
+	var b strings.Builder
+	p := NewParser(collect(&b))
+	for i := 0; i < len(parserDoc); i++ {
+		if err := p.Feed([]byte{parserDoc[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Fatalf("byte by byte:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
+
+func TestParserEmitsCompleteElements(t *testing.T) {
+	var names []string
+	p := NewParser(func(e Element) error {
+		if e, ok := e.(*StartElement); ok {
+			names = append(names, e.Name())
+		}
+		return nil
+	})
+
+	p.Feed([]byte("<stream><message to="))
+	if strings.Join(names, ",") != "stream" {
+		t.Fatalf("unexpected elements %q", names)
+	}
+	p.Feed([]byte(`"a">`))
+	if strings.Join(names, ",") != "stream,message" {
+		t.Fatalf("unexpected elements %q", names)
+	}
+}
+
+func TestParserKeepRaw(t *testing.T) {
+	var b strings.Builder
+	var p *Parser
+	p = NewParser(func(e Element) error {
+		b.Write(p.Reader().Raw())
+		return nil
+	})
+	p.Reader().SetKeepRaw(true)
+
+	const str = `<a x="1"> <!-- c --><b>text</b></a>`
+	for i := 0; i < len(str); i += 3 {
+		end := i + 3
+		if end > len(str) {
+			end = len(str)
+		}
+		p.Feed([]byte(str[i:end]))
+	}
+	p.Close()
+
+	if b.String() != str {
+		t.Fatalf("unexpected raw %q", b.String())
+	}
+}
+
+func TestParserError(t *testing.T) {
+	errStop := errors.New("stop")
+	p := NewParser(func(e Element) error {
+		return errStop
+	})
+	if err := p.Feed([]byte("<a><b>")); err != errStop {
+		t.Fatalf("expected errStop. Got %v", err)
+	}
+	if err := p.Feed([]byte("<c>")); err != errStop {
+		t.Fatalf("expected errStop. Got %v", err)
+	}
+
+	p = NewParser(func(e Element) error { return nil })
+	p.Close()
+	if err := p.Feed([]byte("<a>")); err != ErrParserClosed {
+		t.Fatalf("expected ErrParserClosed. Got %v", err)
+	}
+}
+
+func TestParserTruncated(t *testing.T) {
+	var names []string
+	p := NewParser(func(e Element) error {
+		if s, ok := e.(*StartElement); ok {
+			names = append(names, s.Name())
+		}
+		return nil
+	})
+	if err := p.Feed([]byte(`<a><b x="1`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF. Got %v", err)
+	}
+	if len(names) != 1 || names[0] != "a" {
+		t.Fatalf("unexpected elements %q", names)
+	}
+
+	// trailing text is complete at the end of the input
+	p = NewParser(func(e Element) error { return nil })
+	p.Feed([]byte("<a>text"))
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkParserSmallChunks(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("<doc><text>")
+	sb.WriteString(strings.Repeat("text without markup ", 1<<16))
+	sb.WriteString("</text><!--")
+	sb.WriteString(strings.Repeat("a long comment > ", 1<<16))
+	sb.WriteString("--><![CDATA[")
+	sb.WriteString(strings.Repeat("<p>markup</p>", 1<<16))
+	sb.WriteString("]]></doc>")
+	data := []byte(sb.String())
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p := NewParser(func(e Element) error { return nil })
+		for off := 0; off < len(data); off += 512 {
+			end := off + 512
+			if end > len(data) {
+				end = len(data)
+			}
+			if err := p.Feed(data[off:end]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := p.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
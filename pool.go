@@ -0,0 +1,93 @@
+package xml
+
+import (
+	"io"
+	"sync"
+)
+
+var readerPool = sync.Pool{
+	New: func() interface{} {
+		return NewReader(nil)
+	},
+}
+
+// AcquireReader returns a Reader reading from rd from the pool.
+//
+// The reader has the default settings.
+// Return it to the pool with ReleaseReader when it's no longer needed.
+func AcquireReader(rd io.Reader) *Reader {
+	r := readerPool.Get().(*Reader)
+	r.Reset(rd)
+	return r
+}
+
+// ReleaseReader returns the Reader acquired with AcquireReader to the pool.
+//
+// Neither the reader nor the elements read from it may be used after releasing it.
+func ReleaseReader(r *Reader) {
+	r.Reset(nil)
+	r.defaults()
+	readerPool.Put(r)
+}
+
+// Reset discards the state of the reader and makes it read from rd,
+// reusing its buffers.
+//
+// The settings of the reader, like the whitespace policy
+// or the progress function, are kept.
+// The previous document's DTD, repairs and element count are discarded.
+func (r *Reader) Reset(rd io.Reader) {
+	r.release()
+	for _, e := range r.queue {
+		if end, ok := e.(*EndElement); ok {
+			releaseEnd(end)
+		} else if s, ok := e.(*StartElement); ok {
+			releaseStart(s)
+		} else if t, ok := e.(*TextElement); ok {
+			releaseText(t)
+		}
+	}
+
+	s := r.r
+	*s = scanner{
+		rd:     rd,
+		buf:    s.buf[:cap(s.buf)],
+		ctx:    s.ctx,
+		tmp:    s.tmp[:0],
+		record: s.record,
+		raw:    s.raw[:0],
+		html:   s.html,
+	}
+
+	r.err = nil
+	r.n = assignTarget{}
+	r.tok = 0
+	r.pos = 0
+	r.buf = r.buf[:0]
+	r.dtd = nil
+	r.ebuf = r.ebuf[:0]
+	r.expanded = 0
+	r.repairs = nil
+	r.rawText = false
+	r.stack = r.stack[:0]
+	r.queue = r.queue[:0]
+	r.src = nil
+	r.reported = 0
+	r.elements = 0
+	r.done = false
+}
+
+// defaults restores the default settings.
+func (r *Reader) defaults() {
+	r.ws = WhitespaceDropBlank
+	r.SetKeepRaw(false)
+	r.SetHTML(false)
+	r.repair = false
+	r.entityLimit = defaultEntityLimit
+	r.resolver = nil
+	r.r.ctx = nil
+	r.names = nil
+	r.handler = nil
+	r.progress = nil
+	r.interval = 0
+}
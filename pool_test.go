@@ -0,0 +1,80 @@
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReaderReset(t *testing.T) {
+	r := NewReaderSize(strings.NewReader(`<doc><a>`), 16)
+	r.SetRepair(true)
+	r.SetWhitespace(WhitespaceTrim)
+	readAll(r)
+	if len(r.Repairs()) == 0 {
+		t.Fatal("expected repairs")
+	}
+
+	const str = `<doc> <a x="1"> text </a><b/></doc>`
+	r.Reset(strings.NewReader(str))
+	if len(r.Repairs()) != 0 || r.Offset() != 0 {
+		t.Fatal("the state hasn't been reset")
+	}
+
+	expected := NewReader(strings.NewReader(str))
+	expected.SetWhitespace(WhitespaceTrim)
+	if got, want := readAll(r), readAll(expected); got != want {
+		t.Fatalf("\n%s\nexpected:\n%s", got, want)
+	}
+}
+
+func TestReaderResetProgress(t *testing.T) {
+	var done int
+	r := NewReader(strings.NewReader(`<a/>`))
+	r.SetProgress(1, func(p Progress) {
+		if p.Done {
+			done++
+		}
+	})
+	readAll(r)
+
+	r.Reset(strings.NewReader(`<b/>`))
+	readAll(r)
+	if done != 2 {
+		t.Fatalf("expected 2 done reports. Got %d", done)
+	}
+}
+
+func TestAcquireReader(t *testing.T) {
+	r := AcquireReader(strings.NewReader(`<a> </a>`))
+	r.SetWhitespace(WhitespacePreserve)
+	readAll(r)
+	ReleaseReader(r)
+
+	r = AcquireReader(strings.NewReader(`<a> </a>`))
+	defer ReleaseReader(r)
+	if got := readAll(r); got != "<a></a>" {
+		t.Fatalf("the settings haven't been reset: %s", got)
+	}
+}
+
+var smallPart = []byte(`<?xml version="1.0"?><sst count="2"><si><t>one</t></si><si><t>two</t></si></sst>`)
+
+func BenchmarkNewReaderSmall(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(smallPart))
+		for r.Next() {
+		}
+	}
+}
+
+func BenchmarkAcquireReaderSmall(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := AcquireReader(bytes.NewReader(smallPart))
+		for r.Next() {
+		}
+		ReleaseReader(r)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package xml
+
+// raceEnabled is set when testing with the race detector,
+// which makes sync.Pool drop items randomly.
+const raceEnabled = true
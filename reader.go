@@ -1,44 +1,143 @@
 package xml
 
 import (
-	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"strconv"
 )
 
 // Reader represents a XML reader.
 type Reader struct {
-	r   *bufio.Reader
+	r   *scanner
 	err error
 	e   Element
-	n   *string
+	n   assignTarget
+	tok int   // position in r.raw where the last element starts.
+	pos int64 // offset in the input where the last element starts.
+
+	buf     []byte
+	sub     []byte // holds the content read by ReadText and ReadInnerXML.
+	ws      Whitespace
+	keepRaw bool
+
+	dtd         *DTD
+	ebuf        []byte
+	entityLimit int
+	expanded    int
+	resolver    EntityResolver
+
+	html    bool
+	repair  bool
+	repairs []Repair
+	rawText bool      // the next text is the content of a raw text element.
+	stack   [][]byte  // names of the open elements.
+	queue   []Element // elements to return before reading more.
+
+	src     ElementSource // produces the elements instead of r.r (see NewSourceReader).
+	names   *Names        // interns the names of the elements.
+	handler Handler       // receives the comments, CDATA sections and processing instructions.
+
+	progress func(Progress)
+	interval int64 // bytes between progress reports.
+	reported int64 // bytes consumed in the last progress report.
+	elements int64
+	done     bool // the end of the document has been reported.
 }
 
+// Whitespace defines how the Reader handles the whitespaces of text nodes.
+type Whitespace uint8
+
+const (
+	// WhitespaceDropBlank reports the text nodes as they are found
+	// but drops the ones made only of whitespaces. This is the default.
+	WhitespaceDropBlank Whitespace = iota
+	// WhitespacePreserve reports every text node as it is found,
+	// including the ones made only of whitespaces.
+	WhitespacePreserve
+	// WhitespaceTrim removes the leading and trailing whitespaces
+	// of the text nodes, dropping the ones that become empty.
+	WhitespaceTrim
+)
+
 // NewReader returns a initialized reader.
 func NewReader(r io.Reader) *Reader {
+	return NewReaderSize(r, defaultBufferSize)
+}
+
+// NewReaderSize returns a reader whose buffer has at least the specified size.
+//
+// The buffer grows if a single token doesn't fit in it.
+func NewReaderSize(r io.Reader, size int) *Reader {
 	return &Reader{
-		r: bufio.NewReaderSize(r, 2<<12),
+		r:           newScanner(r, size),
+		entityLimit: defaultEntityLimit,
 	}
 }
 
 // Element returns the last readed element.
+//
+// The element belongs to the reader and it is only valid until the next
+// call to Next, which reuses it. Use String to keep its contents.
 func (r *Reader) Element() Element {
 	return r.e
 }
 
+// SetWhitespace sets how the whitespaces of text nodes are handled.
+func (r *Reader) SetWhitespace(ws Whitespace) {
+	r.ws = ws
+}
+
+// SetKeepRaw makes the reader keep the exact bytes every element
+// has been read from, accessible with Reader.Raw after each call to Next.
+// Start and end elements keep them too, accessible with Element.Raw.
+//
+// The raw bytes of an element include anything found between
+// the previous element and itself, like whitespaces, comments
+// or processing instructions. So writing Reader.Raw after every call
+// to Next reproduces the input.
+func (r *Reader) SetKeepRaw(keep bool) {
+	r.keepRaw = keep
+	r.r.record = keep
+}
+
+// Raw returns the bytes consumed by the last call to Next.
+//
+// When Next returns false Raw holds the bytes found after the last element.
+// Raw is only filled when the reader keeps the raw bytes (see SetKeepRaw).
+func (r *Reader) Raw() []byte {
+	return r.r.raw
+}
+
+// Offset returns the position in the input where the last element starts.
+//
+// For TextElements the position includes the whitespaces preceding the text.
+func (r *Reader) Offset() int64 {
+	return r.pos
+}
+
 // Error return the last error.
 func (r *Reader) Error() error {
 	return r.err
 }
 
 func (r *Reader) release() {
+	if r.r.record {
+		r.r.raw = r.r.raw[:0]
+		r.tok = 0
+	}
 	if r.e == nil {
 		return
 	}
 
-	if e, ok := r.e.(*StartElement); ok {
-		releaseStart(e)
-	} else if e, ok := r.e.(*EndElement); ok {
-		releaseEnd(e)
+	if r.src == nil { // the elements of a source are not pooled
+		if e, ok := r.e.(*StartElement); ok {
+			releaseStart(e)
+		} else if e, ok := r.e.(*EndElement); ok {
+			releaseEnd(e)
+		} else if t, ok := r.e.(*TextElement); ok {
+			releaseText(t)
+		}
 	}
 	r.e = nil
 }
@@ -47,38 +146,201 @@ func (r *Reader) release() {
 func (r *Reader) Next() bool {
 	r.release()
 
+	if ctx := r.r.ctx; ctx != nil && r.err == nil {
+		if r.err = ctx.Err(); r.err != nil {
+			r.report(false)
+			return false
+		}
+	}
+
+	if len(r.queue) > 0 {
+		r.e = r.dequeue()
+		if r.names != nil {
+			r.intern(r.e)
+		}
+		r.report(true)
+		return true
+	}
+	if r.src != nil {
+		return r.nextFromSource()
+	}
+
 	var c byte
 	for r.e == nil && r.err == nil {
-		c, r.err = skipWS(r.r)
-		if r.err == nil {
-			switch c { // get next token
-			case '<': // new element
-				r.next()
-			default: // text string
+		if r.rawText {
+			r.mark(0)
+			if r.err = r.readRawText(); r.err == nil || r.err == io.EOF {
+				r.err = nil
+				if len(trimWS(r.buf)) > 0 || (len(r.buf) > 0 && r.ws == WhitespacePreserve) {
+					r.text(r.buf)
+				}
+			}
+			if r.err != errIncomplete { // resumed on the next feed
+				r.rawText = false
+			}
+			continue
+		}
+
+		r.buf = r.buf[:0]
+		for { // whitespaces preceding the next token
+			c, r.err = r.r.ReadByte()
+			if r.err != nil || c > 32 {
+				break
+			}
+			r.buf = append(r.buf, c)
+		}
+
+		switch {
+		case r.err != nil:
+			if r.err == errIncomplete { // no token started after the skipped ones
+				r.mark(len(r.buf))
+			}
+			if r.err == io.EOF && len(r.buf) > 0 && r.ws == WhitespacePreserve {
+				r.err = nil
+				r.mark(len(r.buf))
+				r.text(r.buf)
+			}
+		case c == '<': // new element
+			if len(r.buf) > 0 && r.ws == WhitespacePreserve {
 				r.r.UnreadByte()
-				t, err := r.r.ReadString('<') // read until a new element starts (or EOF is reached)
-				if err != nil {
-					r.err = err
-				} else {
-					t = t[:len(t)-1]
-					if r.n != nil {
-						*r.n, r.n = t, nil
-					} else {
-						tt := TextElement(t)
-						r.e = &tt
-					}
-					r.r.UnreadByte()
+				r.mark(len(r.buf))
+				r.text(r.buf)
+			} else {
+				r.mark(1)
+				r.next()
+				if r.e != nil && r.tracking() {
+					r.balance()
 				}
 			}
+		default: // text string
+			r.r.UnreadByte()
+			r.mark(len(r.buf))
+			// read until a new element starts (or EOF is reached)
+			r.buf, r.err = r.r.readText(r.buf)
+			if r.err == io.EOF { // trailing text. EOF is returned in the next call.
+				r.err = nil
+			}
+			if r.err == nil {
+				r.text(r.buf)
+			}
+		}
+	}
+
+	if r.err == io.EOF && len(r.stack) > 0 {
+		r.closeOpen()
+	}
+
+	if r.names != nil && r.e != nil {
+		r.intern(r.e)
+	}
+	if r.keepRaw && r.e != nil && len(r.queue) == 0 {
+		r.setRaw(r.e)
+	}
+
+	ok := r.e != nil && r.err == nil
+	if (r.progress != nil || ok) && r.err != errIncomplete {
+		r.report(ok)
+	}
+
+	return ok
+}
+
+// mark sets the start of the current element n bytes before
+// the last byte read.
+func (r *Reader) mark(n int) {
+	r.pos = r.r.n - int64(n)
+	if r.r.record {
+		r.tok = len(r.r.raw) - n
+	}
+}
+
+// text handles a text node normalizing its line endings
+// and applying the whitespace policy.
+func (r *Reader) text(b []byte) {
+	b = normalizeNewlines(b)
+	if r.html && !r.rawText {
+		b = unescapeHTML(b[:0], b)
+	}
+
+	if r.dtd != nil && len(r.dtd.Entities) > 0 && r.entityLimit > 0 && bytes.IndexByte(b, '&') >= 0 {
+		r.ebuf, r.err = r.expand(r.ebuf[:0], b)
+		if r.err != nil {
+			return
 		}
+		b = r.ebuf
 	}
 
-	return r.e != nil && r.err == nil
+	if r.ws == WhitespaceTrim {
+		b = trimWS(b)
+		if len(b) == 0 {
+			return
+		}
+	}
+
+	if r.n != (assignTarget{}) {
+		r.err = r.n.assign(b)
+		r.n = assignTarget{}
+	} else {
+		t := textPool.Get().(*TextElement)
+		*t = append((*t)[:0], b...)
+		r.e = t
+	}
+}
+
+func (r *Reader) setRaw(e Element) {
+	switch e := e.(type) {
+	case *StartElement:
+		e.raw = append(e.raw[:0], r.r.raw...)
+	case *EndElement:
+		e.raw = append(e.raw[:0], r.r.raw...)
+	}
+}
+
+// assignTarget is where the next text is assigned to instead of returning it.
+type assignTarget struct {
+	s *string
+	b *[]byte
+	i *int64
+	f *float64
+}
+
+func (a assignTarget) assign(b []byte) (err error) {
+	switch {
+	case a.s != nil:
+		*a.s = string(b)
+	case a.b != nil:
+		*a.b = append((*a.b)[:0], b...)
+	case a.i != nil:
+		*a.i, err = strconv.ParseInt(b2s(trimWS(b)), 10, 64)
+	case a.f != nil:
+		*a.f, err = strconv.ParseFloat(b2s(trimWS(b)), 64)
+	}
+	return err
 }
 
 // AssignNext will assign the next TextElement to ptr.
 func (r *Reader) AssignNext(ptr *string) {
-	r.n = ptr
+	r.n = assignTarget{s: ptr}
+}
+
+// AssignNextBytes will copy the next TextElement to ptr
+// reusing the memory of the slice.
+func (r *Reader) AssignNextBytes(ptr *[]byte) {
+	r.n = assignTarget{b: ptr}
+}
+
+// AssignNextInt will parse the next TextElement as a base 10 integer into ptr.
+//
+// If the text is not a valid integer the reader stops with the parsing error.
+func (r *Reader) AssignNextInt(ptr *int64) {
+	r.n = assignTarget{i: ptr}
+}
+
+// AssignNextFloat will parse the next TextElement as a floating point number into ptr.
+//
+// If the text is not a valid number the reader stops with the parsing error.
+func (r *Reader) AssignNextFloat(ptr *float64) {
+	r.n = assignTarget{f: ptr}
 }
 
 // skip reads until the next end tag '>'
@@ -87,27 +349,74 @@ func (r *Reader) skip() error {
 	return err
 }
 
+// skipUntil reads until seq is found.
+func (r *Reader) skipUntil(seq string) (err error) {
+	r.buf, err = r.r.readUntil(r.buf[:0], seq)
+	return err
+}
+
+// directive handles the constructs starting with `<!`.
+func (r *Reader) directive() error {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	switch c {
+	case '-': // comment
+		if c, err = r.r.ReadByte(); err != nil {
+			return err
+		}
+		if c != '-' {
+			return fmt.Errorf("xml: expected '-' after '<!-'. Got %q", c)
+		}
+		err = r.skipUntil("-->")
+		if err == nil && r.handler != nil {
+			err = r.comment()
+		}
+		return err
+	case '[': // CDATA section
+		err = r.skipUntil("]]>")
+		if err == nil && r.handler != nil {
+			err = r.cdata()
+		}
+		return err
+	case 'D':
+		if b, err := r.r.peek(6); err == nil && b2s(b) == "OCTYPE" {
+			r.r.advance(6)
+			return r.parseDoctype()
+		}
+	}
+	r.r.UnreadByte()
+	return r.skip()
+}
+
 // next will read the next byte after finding '<'
 func (r *Reader) next() {
 	var c byte
-	c, r.err = skipWS(r.r)
+	c, r.err = r.r.skipWS()
 	if r.err == nil {
 		switch c {
 		case '/':
-			r.e = endPool.Get().(*EndElement)
+			e := endPool.Get().(*EndElement)
+			r.e, r.err = e, e.parse(r.r)
 		case '!':
-			r.err = r.skip()
+			r.err = r.directive()
 		case '?':
-			r.err = r.skip()
+			r.err = r.skipUntil("?>")
+			if r.err == nil && r.handler != nil {
+				r.err = r.procInst()
+			}
 		default:
-			r.e = startPool.Get().(*StartElement)
 			r.r.UnreadByte()
-		}
-		if r.err == nil && r.e != nil {
-			r.err = r.e.parse(r.r)
-			if r.err != nil {
-				r.e = nil
+			s := startPool.Get().(*StartElement)
+			r.e, r.err = s, s.parse(r.r)
+			if r.err == nil && r.dtd != nil {
+				r.err = r.expandAttrs(s)
 			}
 		}
+		if r.err != nil {
+			r.e = nil
+		}
 	}
 }
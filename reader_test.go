@@ -259,3 +259,135 @@ func benchXML(b *testing.B, d *xml.Decoder) {
 		b.Fatalf("Expected 4 books. Got %d", books)
 	}
 }
+
+func TestReaderKeepRaw(t *testing.T) {
+	const str = `<?xml version="1.0"?>
+<!-- signed -->
+<Invoice   xmlns = 'urn:x'>
+	<ID>A&amp;1</ID >
+	<Note/><Total  currency="EUR" >10.00</Total>
+</Invoice>
+
+`
+
+	var sb strings.Builder
+	w := NewWriter(&sb)
+
+	r := NewReader(strings.NewReader(str))
+	r.SetKeepRaw(true)
+	for r.Next() {
+		var err error
+		if _, ok := r.Element().(*TextElement); ok {
+			_, err = sb.Write(r.Raw())
+		} else {
+			err = w.WriteRaw(r.Element())
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.Error() != io.EOF {
+		t.Fatal(r.Error())
+	}
+	sb.Write(r.Raw())
+
+	if sb.String() != str {
+		t.Fatalf("Unexpected output:\n%q\nExpected:\n%q", sb.String(), str)
+	}
+}
+
+func collectText(ws Whitespace, str string) []string {
+	var texts []string
+	r := NewReader(strings.NewReader(str))
+	r.SetWhitespace(ws)
+	for r.Next() {
+		if e, ok := r.Element().(*TextElement); ok {
+			texts = append(texts, e.String())
+		}
+	}
+	return texts
+}
+
+func TestReaderWhitespace(t *testing.T) {
+	const str = "<p>\n  <b>bold</b> and <i>italic</i>  </p>\n trailing "
+
+	for _, tc := range []struct {
+		ws       Whitespace
+		expected []string
+	}{
+		{WhitespaceDropBlank, []string{"bold", " and ", "italic", "\n trailing "}},
+		{WhitespacePreserve, []string{"\n  ", "bold", " and ", "italic", "  ", "\n trailing "}},
+		{WhitespaceTrim, []string{"bold", "and", "italic", "trailing"}},
+	} {
+		texts := collectText(tc.ws, str)
+		if strings.Join(texts, "|") != strings.Join(tc.expected, "|") {
+			t.Fatalf("Unexpected texts with policy %d: %q. Expected %q", tc.ws, texts, tc.expected)
+		}
+	}
+}
+
+func TestReaderTrailingText(t *testing.T) {
+	texts := collectText(WhitespaceDropBlank, "<a/>end")
+	if len(texts) != 1 || texts[0] != "end" {
+		t.Fatalf("Unexpected texts: %q", texts)
+	}
+
+	texts = collectText(WhitespacePreserve, "<a/>  ")
+	if len(texts) != 1 || texts[0] != "  " {
+		t.Fatalf("Unexpected texts: %q", texts)
+	}
+}
+
+func TestReaderWindowsLineEndings(t *testing.T) {
+	const unix = "<?xml version=\"1.0\"?>\n<sheet>\n\t<row\n r=\"1\"\tspans=\"1:2\">\n\t\t<c\tt=\"s\"\n/><v>line 1\nline 2\n</v>\n\t</row\n>\n</sheet>\n"
+	windows := strings.ReplaceAll(unix, "\n", "\r\n")
+	mac := strings.ReplaceAll(unix, "\n", "\r")
+
+	expected := readAll(NewReader(strings.NewReader(unix)))
+	if !strings.Contains(expected, "<row r=1 spans=1:2>") || !strings.Contains(expected, "<c t=s/>") {
+		t.Fatalf("whitespaces not recognized: %s", expected)
+	}
+
+	for _, str := range []string{windows, mac} {
+		if got := readAll(NewReader(strings.NewReader(str))); got != expected {
+			t.Fatalf("%q:\n%s\nexpected:\n%s", str, got, expected)
+		}
+	}
+
+	// the raw bytes are kept as they are
+	r := NewReader(strings.NewReader(windows))
+	r.SetKeepRaw(true)
+	var raw []byte
+	for r.Next() {
+		raw = append(raw, r.Raw()...)
+	}
+	raw = append(raw, r.Raw()...)
+	if string(raw) != windows {
+		t.Fatalf("unexpected raw bytes %q", raw)
+	}
+}
+
+func TestReaderAttrNormalization(t *testing.T) {
+	const str = "<a v=\"one\r\ntwo\tthree\nfour\rfive&#10;\"/>"
+
+	r := NewReader(strings.NewReader(str))
+	if !r.Next() {
+		t.Fatal(r.Error())
+	}
+	v := r.Element().(*StartElement).Attrs().Get("v").Value()
+	if v != "one two three four five&#10;" {
+		t.Fatalf("unexpected value %q", v)
+	}
+}
+
+func TestEndElementName(t *testing.T) {
+	for _, str := range []string{"</a:b>", "</a:b  >", "</\ta:b\r\n>"} {
+		r := NewReader(strings.NewReader(str))
+		if !r.Next() {
+			t.Fatal(r.Error())
+		}
+		if name := r.Element().(*EndElement).Name(); name != "a:b" {
+			t.Fatalf("%q: unexpected name %q", str, name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package xml
+
+import "fmt"
+
+// RepairKind is the kind of a repair made by the Reader.
+type RepairKind uint8
+
+const (
+	// RepairMissingEnd is an EndElement synthesized for an element
+	// left open when one of its ancestors was closed.
+	RepairMissingEnd RepairKind = iota
+	// RepairUnclosed is an EndElement synthesized for an element still open at EOF.
+	RepairUnclosed
+	// RepairUnmatchedEnd is an end tag dropped because its element wasn't open.
+	RepairUnmatchedEnd
+)
+
+func (k RepairKind) String() string {
+	switch k {
+	case RepairMissingEnd:
+		return "missing end tag"
+	case RepairUnclosed:
+		return "unclosed element"
+	case RepairUnmatchedEnd:
+		return "unmatched end tag"
+	}
+	return "unknown repair"
+}
+
+// Repair is a change made by the Reader to balance the elements.
+type Repair struct {
+	Kind RepairKind
+	// Name is the name of the element.
+	Name string
+	// Offset is the position in the input where the repair was made.
+	Offset int64
+}
+
+func (r Repair) String() string {
+	return fmt.Sprintf("%s %s at offset %d", r.Kind, r.Name, r.Offset)
+}
+
+// SetRepair makes the reader keep the stack of open elements
+// to always report a balanced stream of elements.
+//
+// An end tag closing an ancestor of the open elements closes them
+// with synthetic EndElements (see EndElement.IsSynthetic), and so
+// are the elements still open at EOF. End tags without a matching
+// open element are dropped. The repairs made are reported by Repairs.
+func (r *Reader) SetRepair(repair bool) {
+	r.repair = repair
+}
+
+// Repairs returns the repairs made so far by the reader.
+//
+// The repairs are only recorded in repair mode (see SetRepair).
+func (r *Reader) Repairs() []Repair {
+	return r.repairs
+}
+
+// tracking reports whether the reader keeps the stack of open elements.
+func (r *Reader) tracking() bool {
+	return r.repair || r.html
+}
+
+// repaired records a repair if the repair mode is enabled.
+func (r *Reader) repaired(kind RepairKind, name []byte) {
+	if !r.repair {
+		return
+	}
+	offset := r.pos
+	if kind == RepairUnclosed {
+		offset = r.r.n
+	}
+	r.repairs = append(r.repairs, Repair{
+		Kind:   kind,
+		Name:   string(name),
+		Offset: offset,
+	})
+}
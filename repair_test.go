@@ -0,0 +1,65 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReaderRepair(t *testing.T) {
+	const str = `<doc><a><b>text</a></c><d/><e>`
+
+	r := NewReader(strings.NewReader(str))
+	r.SetRepair(true)
+
+	var b strings.Builder
+	for r.Next() {
+		if e, ok := r.Element().(*EndElement); ok && e.IsSynthetic() {
+			b.WriteString("~")
+		}
+		b.WriteString(r.Element().String())
+	}
+
+	const expected = `<doc><a><b>text~</b></a><d/><e>~</e>~</doc>`
+	if b.String() != expected {
+		t.Fatalf("\n%s\nexpected:\n%s", b.String(), expected)
+	}
+
+	var repairs []string
+	for _, rep := range r.Repairs() {
+		repairs = append(repairs, rep.String())
+	}
+	expectedRepairs := []string{
+		"missing end tag b at offset 15",
+		"unmatched end tag c at offset 19",
+		"unclosed element e at offset 30",
+		"unclosed element doc at offset 30",
+	}
+	if strings.Join(repairs, "|") != strings.Join(expectedRepairs, "|") {
+		t.Fatalf("unexpected repairs %q", repairs)
+	}
+}
+
+func TestReaderRepairBalanced(t *testing.T) {
+	const str = `<doc><a x="1"><b/></a>text</doc>`
+
+	r := NewReader(strings.NewReader(str))
+	r.SetRepair(true)
+	got := readAll(r)
+
+	if expected := readAll(NewReader(strings.NewReader(str))); got != expected {
+		t.Fatalf("\n%s\nexpected:\n%s", got, expected)
+	}
+	if len(r.Repairs()) != 0 {
+		t.Fatalf("unexpected repairs %v", r.Repairs())
+	}
+}
+
+func TestReaderHTMLNoRepairs(t *testing.T) {
+	r := NewReader(strings.NewReader(`<div><p>text</span></div>`))
+	r.SetHTML(true)
+	readAll(r)
+
+	if len(r.Repairs()) != 0 {
+		t.Fatalf("unexpected repairs without repair mode %v", r.Repairs())
+	}
+}
@@ -0,0 +1,239 @@
+package xml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+const (
+	defaultBufferSize = 2 << 12
+	maxEmptyReads     = 100
+)
+
+// scanner reads the input into its own buffer so the delimiters can be
+// found scanning many bytes at once, optionally recording
+// every byte consumed so the original input can be reproduced.
+type scanner struct {
+	rd   io.Reader
+	buf  []byte
+	r, w int // read and write positions in buf.
+	err  error
+	ctx  context.Context // stops the reads once done.
+
+	tmp []byte // holds the result of ReadBytes when it spans multiple reads.
+
+	n int64 // number of bytes consumed.
+
+	record bool
+	raw    []byte
+
+	// push is set when the input is fed with feed instead of read from rd.
+	push   bool
+	closed bool // no more input will be fed.
+
+	html bool // attributes are parsed as HTML.
+}
+
+// errIncomplete is returned by a push scanner when it runs out
+// of input in the middle of a token.
+var errIncomplete = errors.New("xml: incomplete input")
+
+func newScanner(rd io.Reader, size int) *scanner {
+	if size < 16 {
+		size = 16
+	}
+	return &scanner{
+		rd:  rd,
+		buf: make([]byte, size),
+	}
+}
+
+// fill reads more data into the buffer.
+//
+// The last byte consumed is kept so it can be unread.
+// fill only returns an error if no data could be read.
+func (s *scanner) fill() error {
+	if s.push {
+		if s.closed {
+			return io.EOF
+		}
+		return errIncomplete
+	}
+
+	if s.r > 1 {
+		copy(s.buf, s.buf[s.r-1:s.w])
+		s.w -= s.r - 1
+		s.r = 1
+	}
+	if s.w == len(s.buf) {
+		s.buf = append(s.buf, make([]byte, len(s.buf))...)
+	}
+
+	if s.ctx != nil && s.err == nil {
+		s.err = s.ctx.Err()
+	}
+
+	for i := 0; i < maxEmptyReads && s.err == nil; i++ {
+		n, err := s.rd.Read(s.buf[s.w:])
+		s.w += n
+		s.err = err
+		if n > 0 {
+			return nil
+		}
+	}
+	if s.err == nil {
+		s.err = io.ErrNoProgress
+	}
+	return s.err
+}
+
+// feed appends b to the input of a push scanner
+// discarding the bytes already consumed.
+func (s *scanner) feed(b []byte) {
+	if s.r > 0 {
+		s.w = copy(s.buf, s.buf[s.r:s.w])
+		s.r = 0
+	}
+	s.buf = append(s.buf[:s.w], b...)
+	s.w = len(s.buf)
+}
+
+// advance consumes the next n buffered bytes.
+func (s *scanner) advance(n int) {
+	if s.record {
+		s.raw = append(s.raw, s.buf[s.r:s.r+n]...)
+	}
+	s.r += n
+	s.n += int64(n)
+}
+
+func (s *scanner) ReadByte() (byte, error) {
+	if s.r == s.w {
+		if err := s.fill(); err != nil {
+			return 0, err
+		}
+	}
+	c := s.buf[s.r]
+	s.advance(1)
+	return c, nil
+}
+
+func (s *scanner) UnreadByte() error {
+	if s.r == 0 {
+		return io.ErrNoProgress
+	}
+	s.r--
+	s.n--
+	if s.record && len(s.raw) > 0 {
+		s.raw = s.raw[:len(s.raw)-1]
+	}
+	return nil
+}
+
+// ReadBytes reads until the first occurrence of delim, returning
+// the bytes read including the delimiter.
+//
+// The returned slice is only valid until the next read.
+func (s *scanner) ReadBytes(delim byte) ([]byte, error) {
+	s.tmp = s.tmp[:0]
+	for {
+		b := s.buf[s.r:s.w]
+		if i := bytes.IndexByte(b, delim); i >= 0 {
+			s.advance(i + 1)
+			if len(s.tmp) == 0 {
+				return b[:i+1], nil
+			}
+			return append(s.tmp, b[:i+1]...), nil
+		}
+		s.tmp = append(s.tmp, b...)
+		s.advance(len(b))
+
+		if err := s.fill(); err != nil {
+			return s.tmp, err
+		}
+	}
+}
+
+// peek returns the next n bytes without consuming them.
+//
+// If there are less than n bytes left peek returns them with an error.
+func (s *scanner) peek(n int) ([]byte, error) {
+	for s.w-s.r < n {
+		if err := s.fill(); err != nil {
+			return s.buf[s.r:s.w], err
+		}
+	}
+	return s.buf[s.r : s.r+n], nil
+}
+
+// skipWS skips the whitespaces returning the next byte.
+func (s *scanner) skipWS() (byte, error) {
+	for {
+		if i := indexNonSpace(s.buf[s.r:s.w]); s.r+i < s.w {
+			s.advance(i + 1)
+			return s.buf[s.r-1], nil
+		}
+		s.advance(s.w - s.r)
+
+		if err := s.fill(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readName appends to dst the bytes found until a whitespace, '>', '/' or '='.
+//
+// The delimiter is not consumed.
+func (s *scanner) readName(dst []byte) ([]byte, error) {
+	for {
+		b := s.buf[s.r:s.w]
+		i := indexNameEnd(b)
+		dst = append(dst, b[:i]...)
+		s.advance(i)
+		if i < len(b) {
+			return dst, nil
+		}
+
+		if err := s.fill(); err != nil {
+			return dst, err
+		}
+	}
+}
+
+// readText appends to dst the bytes found until '<' or EOF.
+//
+// The '<' is not consumed.
+func (s *scanner) readText(dst []byte) ([]byte, error) {
+	for {
+		b := s.buf[s.r:s.w]
+		if i := bytes.IndexByte(b, '<'); i >= 0 {
+			s.advance(i)
+			return append(dst, b[:i]...), nil
+		}
+		dst = append(dst, b...)
+		s.advance(len(b))
+
+		if err := s.fill(); err != nil {
+			return dst, err
+		}
+	}
+}
+
+// readUntil appends to dst the bytes found until seq.
+//
+// seq is consumed but not appended.
+func (s *scanner) readUntil(dst []byte, seq string) ([]byte, error) {
+	n := len(dst)
+	for {
+		b, err := s.ReadBytes(seq[len(seq)-1])
+		dst = append(dst, b...)
+		if err != nil {
+			return dst, err
+		}
+		if len(dst)-n >= len(seq) && b2s(dst[len(dst)-len(seq):]) == seq {
+			return dst[:len(dst)-len(seq)], nil
+		}
+	}
+}
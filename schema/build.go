@@ -0,0 +1,537 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// element is an element declaration.
+type element struct {
+	name     string
+	typ      *typeDef // nil means xs:anyType
+	nillable bool
+	fixed    string
+	hasFixed bool
+}
+
+// typeDef is either a simple or a complex type.
+// When both are nil the type is xs:anyType.
+type typeDef struct {
+	name    string
+	simple  *simpleType
+	complex *complexType
+}
+
+var anyType = &typeDef{name: "anyType"}
+
+type complexType struct {
+	mixed    bool
+	particle *particle // nil means empty content.
+	attrs    []*attribute
+	anyAttr  bool
+	simple   *simpleType // simple content.
+
+	content *contentModel
+}
+
+type attribute struct {
+	name       string
+	typ        *simpleType
+	required   bool
+	prohibited bool
+	fixed      string
+	hasFixed   bool
+}
+
+type particleKind uint8
+
+const (
+	elementParticle particleKind = iota
+	sequenceParticle
+	choiceParticle
+	allParticle
+	anyParticle
+)
+
+// particle is a component of a content model.
+type particle struct {
+	kind     particleKind
+	elem     *element
+	children []*particle
+	min, max int  // max < 0 means unbounded.
+	skip     bool // processContents="skip" in xs:any.
+}
+
+func (s *Schema) globalElement(name string) (*element, error) {
+	if e, ok := s.elements[name]; ok {
+		return e, nil
+	}
+	n, ok := s.elementNodes[name]
+	if !ok {
+		return nil, fmt.Errorf("schema: element %s not declared", name)
+	}
+
+	e := &element{name: name}
+	s.elements[name] = e
+	return e, s.buildElement(n, e)
+}
+
+func (s *Schema) typeByName(name string) (*typeDef, error) {
+	if t, ok := s.types[name]; ok {
+		return t, nil
+	}
+	n, ok := s.typeNodes[name]
+	if !ok {
+		return nil, fmt.Errorf("schema: type %s not declared", name)
+	}
+
+	t := &typeDef{name: name}
+	s.types[name] = t
+	return t, s.buildType(n, t)
+}
+
+// resolveType returns the type referenced by the QName value in n.
+func (s *Schema) resolveType(n *node, value string) (*typeDef, error) {
+	ns, local := n.qname(value)
+	if ns == xsdNamespace {
+		if local == "anyType" {
+			return anyType, nil
+		}
+		if b, ok := builtins[local]; ok {
+			return &typeDef{name: local, simple: b}, nil
+		}
+		return nil, fmt.Errorf("schema: unknown built-in type %s", value)
+	}
+	return s.typeByName(local)
+}
+
+// resolveSimple returns the simple type referenced by the QName value in n.
+func (s *Schema) resolveSimple(n *node, value string) (*simpleType, error) {
+	t, err := s.resolveType(n, value)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.simple != nil:
+		return t.simple, nil
+	case t.complex != nil && t.complex.simple != nil:
+		return t.complex.simple, nil
+	case t == anyType:
+		return builtins["anySimpleType"], nil
+	}
+	return nil, fmt.Errorf("schema: %s is not a simple type", value)
+}
+
+func (s *Schema) buildType(n *node, t *typeDef) (err error) {
+	if n.name == "simpleType" {
+		t.simple, err = s.buildSimple(n)
+		if t.simple != nil {
+			t.simple.name = t.name
+		}
+	} else {
+		t.complex = &complexType{}
+		err = s.buildComplex(n, t.complex)
+	}
+	return err
+}
+
+func (s *Schema) buildElement(n *node, e *element) (err error) {
+	e.nillable = n.attr("nillable") == "true"
+	e.fixed, e.hasFixed = n.attr("fixed"), n.hasAttr("fixed")
+
+	switch {
+	case n.hasAttr("type"):
+		e.typ, err = s.resolveType(n, n.attr("type"))
+	case n.child("complexType") != nil:
+		e.typ = &typeDef{complex: &complexType{}}
+		err = s.buildComplex(n.child("complexType"), e.typ.complex)
+		e.typ.complex.compile()
+	case n.child("simpleType") != nil:
+		e.typ = &typeDef{}
+		e.typ.simple, err = s.buildSimple(n.child("simpleType"))
+	}
+	if err != nil {
+		return fmt.Errorf("element %s: %w", e.name, err)
+	}
+
+	return nil
+}
+
+func (s *Schema) buildComplex(n *node, ct *complexType) (err error) {
+	ct.mixed = n.attr("mixed") == "true"
+
+	for _, c := range n.children {
+		switch c.name {
+		case "sequence", "choice", "all", "group":
+			ct.particle, err = s.buildParticle(c)
+		case "simpleContent":
+			err = s.buildSimpleContent(c, ct)
+		case "complexContent":
+			if c.attr("mixed") == "true" {
+				ct.mixed = true
+			}
+			err = s.buildComplexContent(c, ct)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.buildAttrs(n, &ct.attrs, &ct.anyAttr)
+}
+
+func (s *Schema) buildSimpleContent(n *node, ct *complexType) error {
+	d := n.child("extension")
+	if d == nil {
+		d = n.child("restriction")
+	}
+	if d == nil {
+		return fmt.Errorf("schema: simpleContent without derivation")
+	}
+
+	base, err := s.resolveType(d, d.attr("base"))
+	if err != nil {
+		return err
+	}
+	switch {
+	case base.simple != nil:
+		ct.simple = base.simple
+	case base.complex != nil:
+		ct.simple = base.complex.simple
+		ct.attrs = append(ct.attrs, base.complex.attrs...)
+		ct.anyAttr = base.complex.anyAttr
+	}
+	if ct.simple == nil {
+		ct.simple = builtins["anySimpleType"]
+	}
+
+	if d.name == "restriction" {
+		if ct.simple, err = s.restrict(d, ct.simple); err != nil {
+			return err
+		}
+	}
+
+	return s.buildAttrs(d, &ct.attrs, &ct.anyAttr)
+}
+
+func (s *Schema) buildComplexContent(n *node, ct *complexType) error {
+	d := n.child("extension")
+	if d == nil {
+		d = n.child("restriction")
+	}
+	if d == nil {
+		return fmt.Errorf("schema: complexContent without derivation")
+	}
+
+	base, err := s.resolveType(d, d.attr("base"))
+	if err != nil {
+		return err
+	}
+	if base.complex != nil {
+		ct.attrs = append(ct.attrs, base.complex.attrs...)
+		ct.anyAttr = base.complex.anyAttr
+	}
+
+	var own *particle
+	for _, c := range d.children {
+		switch c.name {
+		case "sequence", "choice", "all", "group":
+			if own, err = s.buildParticle(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	if d.name == "extension" && base.complex != nil && base.complex.particle != nil {
+		if base.complex.mixed {
+			ct.mixed = true
+		}
+		if own == nil {
+			own = base.complex.particle
+		} else {
+			own = &particle{
+				kind:     sequenceParticle,
+				children: []*particle{base.complex.particle, own},
+				min:      1,
+				max:      1,
+			}
+		}
+	}
+	ct.particle = own
+
+	return s.buildAttrs(d, &ct.attrs, &ct.anyAttr)
+}
+
+// buildAttrs adds to attrs the attribute declarations found in n.
+//
+// Attributes declared again override the previous declarations.
+func (s *Schema) buildAttrs(n *node, attrs *[]*attribute, anyAttr *bool) error {
+	for _, c := range n.children {
+		switch c.name {
+		case "attribute":
+			a, err := s.buildAttr(c)
+			if err != nil {
+				return err
+			}
+			found := false
+			for i, prev := range *attrs {
+				if prev.name == a.name {
+					(*attrs)[i], found = a, true
+					break
+				}
+			}
+			if !found {
+				*attrs = append(*attrs, a)
+			}
+		case "attributeGroup":
+			_, local := c.qname(c.attr("ref"))
+			g, ok := s.attrGroupNodes[local]
+			if !ok {
+				return fmt.Errorf("schema: attributeGroup %s not declared", local)
+			}
+			if err := s.buildAttrs(g, attrs, anyAttr); err != nil {
+				return err
+			}
+		case "anyAttribute":
+			*anyAttr = true
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) buildAttr(n *node) (a *attribute, err error) {
+	a = &attribute{}
+	if ref := n.attr("ref"); ref != "" {
+		_, local := n.qname(ref)
+		g, err := s.globalAttr(local)
+		if err != nil {
+			return nil, err
+		}
+		*a = *g
+	} else {
+		a.name = n.attr("name")
+		switch {
+		case n.hasAttr("type"):
+			a.typ, err = s.resolveSimple(n, n.attr("type"))
+		case n.child("simpleType") != nil:
+			a.typ, err = s.buildSimple(n.child("simpleType"))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", a.name, err)
+		}
+	}
+
+	switch n.attr("use") {
+	case "required":
+		a.required = true
+	case "prohibited":
+		a.prohibited = true
+	}
+	if n.hasAttr("fixed") {
+		a.fixed, a.hasFixed = n.attr("fixed"), true
+	}
+
+	return a, nil
+}
+
+func (s *Schema) globalAttr(name string) (*attribute, error) {
+	if a, ok := s.attrs[name]; ok {
+		return a, nil
+	}
+	n, ok := s.attrNodes[name]
+	if !ok {
+		return nil, fmt.Errorf("schema: attribute %s not declared", name)
+	}
+	a, err := s.buildAttr(n)
+	if err == nil {
+		s.attrs[name] = a
+	}
+	return a, err
+}
+
+func (s *Schema) buildParticle(n *node) (p *particle, err error) {
+	p = &particle{min: 1, max: 1}
+	if v := n.attr("minOccurs"); v != "" {
+		if p.min, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("schema: invalid minOccurs %q", v)
+		}
+	}
+	if v := n.attr("maxOccurs"); v == "unbounded" {
+		p.max = -1
+	} else if v != "" {
+		if p.max, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("schema: invalid maxOccurs %q", v)
+		}
+	}
+
+	switch n.name {
+	case "element":
+		p.kind = elementParticle
+		if ref := n.attr("ref"); ref != "" {
+			_, local := n.qname(ref)
+			p.elem, err = s.globalElement(local)
+		} else {
+			p.elem = &element{name: n.attr("name")}
+			err = s.buildElement(n, p.elem)
+		}
+	case "any":
+		p.kind = anyParticle
+		p.skip = n.attr("processContents") == "skip"
+	case "group":
+		_, local := n.qname(n.attr("ref"))
+		g, ok := s.groupNodes[local]
+		if !ok {
+			return nil, fmt.Errorf("schema: group %s not declared", local)
+		}
+		for _, c := range g.children {
+			switch c.name {
+			case "sequence", "choice", "all":
+				var gp *particle
+				if gp, err = s.buildParticle(c); err != nil {
+					return nil, err
+				}
+				gp.min, gp.max = p.min, p.max
+				return gp, nil
+			}
+		}
+		return nil, fmt.Errorf("schema: empty group %s", local)
+	case "sequence", "choice", "all":
+		switch n.name {
+		case "sequence":
+			p.kind = sequenceParticle
+		case "choice":
+			p.kind = choiceParticle
+		case "all":
+			p.kind = allParticle
+		}
+		for _, c := range n.children {
+			switch c.name {
+			case "element", "any", "group", "sequence", "choice":
+				var cp *particle
+				if cp, err = s.buildParticle(c); err != nil {
+					return nil, err
+				}
+				p.children = append(p.children, cp)
+			}
+		}
+	}
+
+	return p, err
+}
+
+// buildSimple builds the simpleType n.
+func (s *Schema) buildSimple(n *node) (*simpleType, error) {
+	if d := n.child("restriction"); d != nil {
+		var (
+			base *simpleType
+			err  error
+		)
+		if b := d.attr("base"); b != "" {
+			base, err = s.resolveSimple(d, b)
+		} else if c := d.child("simpleType"); c != nil {
+			base, err = s.buildSimple(c)
+		} else {
+			err = fmt.Errorf("schema: restriction without base")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.restrict(d, base)
+	}
+
+	if d := n.child("list"); d != nil {
+		t := newSimple(listVariety, wsCollapse)
+		var err error
+		if it := d.attr("itemType"); it != "" {
+			t.item, err = s.resolveSimple(d, it)
+		} else if c := d.child("simpleType"); c != nil {
+			t.item, err = s.buildSimple(c)
+		} else {
+			err = fmt.Errorf("schema: list without itemType")
+		}
+		return t, err
+	}
+
+	if d := n.child("union"); d != nil {
+		t := newSimple(unionVariety, wsCollapse)
+		for _, m := range strings.Fields(d.attr("memberTypes")) {
+			mt, err := s.resolveSimple(d, m)
+			if err != nil {
+				return nil, err
+			}
+			t.members = append(t.members, mt)
+		}
+		for _, c := range d.children {
+			if c.name == "simpleType" {
+				mt, err := s.buildSimple(c)
+				if err != nil {
+					return nil, err
+				}
+				t.members = append(t.members, mt)
+			}
+		}
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("schema: simpleType without derivation")
+}
+
+// restrict derives a simple type from base applying the facets in n.
+func (s *Schema) restrict(n *node, base *simpleType) (*simpleType, error) {
+	t := newSimple(base.variety, base.ws)
+	t.base = base
+
+	var patterns []string
+	for _, c := range n.children {
+		v := c.attr("value")
+		var err error
+		switch c.name {
+		case "enumeration":
+			t.enums = append(t.enums, v)
+		case "pattern":
+			patterns = append(patterns, v)
+		case "whiteSpace":
+			switch v {
+			case "preserve":
+				t.ws = wsPreserve
+			case "replace":
+				t.ws = wsReplace
+			case "collapse":
+				t.ws = wsCollapse
+			}
+		case "length":
+			t.length, err = strconv.Atoi(v)
+		case "minLength":
+			t.minLength, err = strconv.Atoi(v)
+		case "maxLength":
+			t.maxLength, err = strconv.Atoi(v)
+		case "totalDigits":
+			t.totalDigits, err = strconv.Atoi(v)
+		case "fractionDigits":
+			t.fractionDigits, err = strconv.Atoi(v)
+		case "minInclusive":
+			t.bounds = append(t.bounds, bound{v, 0, true})
+		case "maxInclusive":
+			t.bounds = append(t.bounds, bound{v, 0, false})
+		case "minExclusive":
+			t.bounds = append(t.bounds, bound{v, 1, true})
+		case "maxExclusive":
+			t.bounds = append(t.bounds, bound{v, 1, false})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("schema: invalid %s %q", c.name, v)
+		}
+	}
+
+	if len(patterns) > 0 {
+		re, err := compilePattern(patterns)
+		if err != nil {
+			return nil, err
+		}
+		t.pattern = re
+	}
+
+	return t, nil
+}
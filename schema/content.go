@@ -0,0 +1,195 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxExpanded limits the copies made of a particle to represent
+// its occurrence constraints. Bigger bounds are relaxed to unbounded.
+const maxExpanded = 256
+
+// state is a state of the content model automaton.
+//
+// A state either matches an element (or any element for wildcards)
+// moving to next, or moves to the eps states without consuming anything.
+type state struct {
+	p    *particle // element or wildcard particle. nil for epsilon states.
+	next int
+	eps  []int
+}
+
+// contentModel validates the sequence of children of an element.
+//
+// Sequences and choices are compiled into a Thompson NFA
+// simulated by the Validator. All groups are checked counting
+// the occurrences of their elements.
+type contentModel struct {
+	states []state
+	start  int
+	final  int
+
+	all []*particle // children of an all group.
+}
+
+func (ct *complexType) compile() {
+	if ct.content != nil || ct.particle == nil {
+		return
+	}
+
+	cm := &contentModel{}
+	if ct.particle.kind == allParticle {
+		cm.all = ct.particle.children
+	} else {
+		cm.start, cm.final = cm.build(ct.particle)
+	}
+	ct.content = cm
+}
+
+func (cm *contentModel) newState() int {
+	cm.states = append(cm.states, state{next: -1})
+	return len(cm.states) - 1
+}
+
+func (cm *contentModel) link(from, to int) {
+	cm.states[from].eps = append(cm.states[from].eps, to)
+}
+
+// build builds the fragment for p returning its start and end states.
+func (cm *contentModel) build(p *particle) (start, end int) {
+	min, max := p.min, p.max
+	if min > maxExpanded {
+		min = maxExpanded
+	}
+	if max > maxExpanded {
+		max = -1
+	}
+
+	start = cm.newState()
+	end = start
+	for i := 0; i < min; i++ {
+		s, e := cm.buildOnce(p)
+		cm.link(end, s)
+		end = e
+	}
+
+	switch {
+	case max < 0: // unbounded
+		s, e := cm.buildOnce(p)
+		cm.link(end, s)
+		cm.link(e, s)
+		last := cm.newState()
+		cm.link(end, last)
+		cm.link(e, last)
+		end = last
+	case max > min:
+		last := cm.newState()
+		for i := min; i < max; i++ {
+			s, e := cm.buildOnce(p)
+			cm.link(end, s)
+			cm.link(end, last)
+			end = e
+		}
+		cm.link(end, last)
+		end = last
+	}
+
+	return start, end
+}
+
+// buildOnce builds the fragment for a single occurrence of p.
+func (cm *contentModel) buildOnce(p *particle) (start, end int) {
+	switch p.kind {
+	case elementParticle, anyParticle:
+		start, end = cm.newState(), cm.newState()
+		cm.states[start].p = p
+		cm.states[start].next = end
+	case sequenceParticle, allParticle: // nested all groups are treated as sequences.
+		start = cm.newState()
+		end = start
+		for _, c := range p.children {
+			s, e := cm.build(c)
+			cm.link(end, s)
+			end = e
+		}
+	case choiceParticle:
+		start, end = cm.newState(), cm.newState()
+		for _, c := range p.children {
+			s, e := cm.build(c)
+			cm.link(start, s)
+			cm.link(e, end)
+		}
+		if len(p.children) == 0 {
+			cm.link(start, end)
+		}
+	}
+	return start, end
+}
+
+// closure adds to set the states reachable from s without consuming elements.
+func (cm *contentModel) closure(set []int, s int) []int {
+	for _, x := range set {
+		if x == s {
+			return set
+		}
+	}
+	set = append(set, s)
+	for _, e := range cm.states[s].eps {
+		set = cm.closure(set, e)
+	}
+	return set
+}
+
+// step moves the states in set matching name into dst.
+//
+// It returns the new set and the particle matched.
+func (cm *contentModel) step(dst, set []int, name string) ([]int, *particle) {
+	var matched *particle
+	for _, s := range set {
+		st := &cm.states[s]
+		if st.p == nil {
+			continue
+		}
+		if st.p.kind == anyParticle || st.p.elem.name == name {
+			if matched == nil || (matched.kind == anyParticle && st.p.kind == elementParticle) {
+				matched = st.p
+			}
+			dst = cm.closure(dst, st.next)
+		}
+	}
+	return dst, matched
+}
+
+func (cm *contentModel) accepts(set []int) bool {
+	for _, s := range set {
+		if s == cm.final {
+			return true
+		}
+	}
+	return false
+}
+
+// expected returns the names of the elements accepted by set.
+func (cm *contentModel) expected(set []int) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range set {
+		p := cm.states[s].p
+		if p == nil {
+			continue
+		}
+		name := "any element"
+		if p.kind == elementParticle {
+			name = p.elem.name
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "no more elements"
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,305 @@
+// Package schema validates XML documents against XML Schema (XSD) definitions.
+//
+// The validation is performed on the stream of elements produced
+// by xml.Reader, so documents are never loaded into memory.
+//
+// The package supports the subset of XML Schema most commonly found in the wild:
+// global and local elements, complex and simple types, sequences, choices,
+// all groups, occurrence constraints, groups and attribute groups,
+// type derivation by extension and restriction, simple content,
+// lists, unions, the facets and the built-in datatypes.
+//
+// Namespaces are matched by the local name of elements and attributes,
+// so the prefixes used in the documents are not checked against the
+// target namespace of the schema. Identity constraints (key, keyref and unique),
+// substitution groups and xsi:type are not supported.
+package schema
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// Schema represents a parsed XML Schema.
+//
+// A Schema is safe to be used concurrently by many Validators.
+type Schema struct {
+	elements map[string]*element
+	types    map[string]*typeDef
+
+	// declarations pending to be resolved.
+	elementNodes   map[string]*node
+	typeNodes      map[string]*node
+	groupNodes     map[string]*node
+	attrGroupNodes map[string]*node
+	attrNodes      map[string]*node
+
+	attrs map[string]*attribute
+}
+
+func newSchema() *Schema {
+	return &Schema{
+		elements:       make(map[string]*element),
+		types:          make(map[string]*typeDef),
+		elementNodes:   make(map[string]*node),
+		typeNodes:      make(map[string]*node),
+		groupNodes:     make(map[string]*node),
+		attrGroupNodes: make(map[string]*node),
+		attrNodes:      make(map[string]*node),
+		attrs:          make(map[string]*attribute),
+	}
+}
+
+// Load parses the XML Schema read from r.
+//
+// The schemas included or imported by r are ignored. Use LoadFile for that.
+func Load(r io.Reader) (*Schema, error) {
+	s := newSchema()
+	root, err := parseTree(r)
+	if err == nil {
+		err = s.collect(root, "")
+	}
+	if err == nil {
+		err = s.resolve()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadFile parses the XML Schema in the file path,
+// including the schemas it includes or imports from the local filesystem.
+func LoadFile(path string) (*Schema, error) {
+	s := newSchema()
+	err := s.loadFile(path, make(map[string]bool))
+	if err == nil {
+		err = s.resolve()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) loadFile(path string, seen map[string]bool) error {
+	path = filepath.Clean(path)
+	if seen[path] {
+		return nil
+	}
+	seen[path] = true
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	root, err := parseTree(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err = s.collect(root, path); err != nil {
+		return err
+	}
+
+	for _, n := range root.children {
+		switch n.name {
+		case "include", "import", "redefine":
+			loc := n.attr("schemaLocation")
+			if loc == "" || strings.Contains(loc, "://") {
+				continue
+			}
+			if !filepath.IsAbs(loc) {
+				loc = filepath.Join(filepath.Dir(path), loc)
+			}
+			if err = s.loadFile(loc, seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// collect registers the global declarations of the schema root.
+func (s *Schema) collect(root *node, path string) error {
+	if root.name != "schema" {
+		return fmt.Errorf("%s: expected schema root element. Got %s", path, root.name)
+	}
+
+	for _, n := range root.children {
+		name := n.attr("name")
+
+		var m map[string]*node
+		switch n.name {
+		case "element":
+			m = s.elementNodes
+		case "complexType", "simpleType":
+			m = s.typeNodes
+		case "group":
+			m = s.groupNodes
+		case "attributeGroup":
+			m = s.attrGroupNodes
+		case "attribute":
+			m = s.attrNodes
+		default:
+			continue
+		}
+		if name == "" {
+			return fmt.Errorf("%s: global %s without name", path, n.name)
+		}
+		m[name] = n
+	}
+
+	return nil
+}
+
+// resolve builds every global declaration.
+func (s *Schema) resolve() (err error) {
+	for name := range s.typeNodes {
+		if _, err = s.typeByName(name); err != nil {
+			return err
+		}
+	}
+	for name := range s.elementNodes {
+		if _, err = s.globalElement(name); err != nil {
+			return err
+		}
+	}
+	for _, t := range s.types {
+		if t.complex != nil {
+			t.complex.compile()
+		}
+	}
+	return nil
+}
+
+// node is a node of the schema document tree.
+type node struct {
+	name     string // local name
+	attrs    []attr
+	children []*node
+	ns       map[string]string // in scope namespace prefixes
+}
+
+type attr struct {
+	name, value string
+}
+
+func (n *node) attr(name string) string {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return a.value
+		}
+	}
+	return ""
+}
+
+func (n *node) hasAttr(name string) bool {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// child returns the first child called name.
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// qname splits the QName value into its namespace and local name.
+func (n *node) qname(value string) (ns, local string) {
+	prefix := ""
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		prefix, value = value[:i], value[i+1:]
+	}
+	return n.ns[prefix], value
+}
+
+// parseTree reads the schema document into a tree of nodes.
+func parseTree(r io.Reader) (*node, error) {
+	var (
+		root  *node
+		stack []*node
+	)
+
+	rd := xml.NewReader(r)
+	for rd.Next() {
+		switch e := rd.Element().(type) {
+		case *xml.StartElement:
+			n := &node{
+				name: localName(e.Name()),
+			}
+			var parent *node
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+				n.ns = parent.ns
+			}
+
+			copied := false
+			e.Attrs().Range(func(kv *xml.KV) {
+				k := kv.Key()
+				v := string(xml.Unescape(nil, kv.ValueBytes()))
+				if k == "xmlns" || strings.HasPrefix(k, "xmlns:") {
+					if !copied {
+						ns := make(map[string]string, len(n.ns)+1)
+						for p, u := range n.ns {
+							ns[p] = u
+						}
+						n.ns, copied = ns, true
+					}
+					n.ns[strings.TrimPrefix(strings.TrimPrefix(k, "xmlns"), ":")] = v
+					return
+				}
+				n.attrs = append(n.attrs, attr{k, v})
+			})
+
+			if parent == nil {
+				if root != nil {
+					return nil, fmt.Errorf("schema: multiple root elements")
+				}
+				root = n
+			} else {
+				parent.children = append(parent.children, n)
+			}
+			if !e.HasEnd() {
+				stack = append(stack, n)
+			}
+		case *xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if err := rd.Error(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("schema: empty document")
+	}
+
+	return root, nil
+}
+
+// localName strips the prefix of name.
+func localName(name string) string {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
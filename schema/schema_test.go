@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+const validInvoice = `<?xml version="1.0"?>
+<inv:Invoice xmlns:inv="urn:example:invoice" version="2.1" status=" final ">
+  <inv:ID>INV-0042</inv:ID>
+  <inv:IssueDate>2020-02-29</inv:IssueDate>
+  <inv:Customer>
+    <inv:VAT>ES123</inv:VAT>
+    <inv:Name>ACME &amp; Co</inv:Name>
+  </inv:Customer>
+  <inv:Line n="1"><inv:Qty>3</inv:Qty><inv:Price currency="EUR">10.50</inv:Price></inv:Line>
+  <inv:Line n="2"><inv:Qty>1</inv:Qty><inv:Price currency="EUR">0.99</inv:Price></inv:Line>
+  <inv:Total currency="EUR">32.49</inv:Total>
+</inv:Invoice>`
+
+func loadInvoice(t *testing.T) *Schema {
+	s, err := LoadFile("testdata/invoice.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validate(s *Schema, doc string) error {
+	return s.Validate(xml.NewReader(strings.NewReader(doc)))
+}
+
+func TestValidateValid(t *testing.T) {
+	s := loadInvoice(t)
+	if err := validate(s, validInvoice); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	s := loadInvoice(t)
+
+	for _, tc := range []struct {
+		old, new string
+		path     string
+		msg      string
+	}{
+		{`<inv:ID>INV-0042</inv:ID>`, `<inv:ID>X-1</inv:ID>`, "/Invoice/ID", "pattern"},
+		{`2020-02-29`, `2021-02-29`, "/Invoice/IssueDate", "not a valid date"},
+		{` version="2.1"`, ``, "/Invoice", "missing required attribute version"},
+		{`status=" final "`, `status="sent"`, "/Invoice", "attribute status"},
+		{`<inv:Qty>3</inv:Qty>`, `<inv:Qty>3000</inv:Qty>`, "/Invoice/Line/Qty", "<= 1000"},
+		{`<inv:Qty>3</inv:Qty>`, `<inv:Qty>0</inv:Qty>`, "/Invoice/Line/Qty", "range"},
+		{`>10.50<`, `>10.505<`, "/Invoice/Line/Price", "fraction digits"},
+		{`currency="EUR">10.50`, `currency="EURO">10.50`, "/Invoice/Line/Price", "length"},
+		{`<inv:Line n="2">`, `<inv:Line>`, "/Invoice/Line", "missing required attribute n"},
+		{`<inv:Total currency="EUR">32.49</inv:Total>`, ``, "/Invoice", "incomplete content. Expected Line, Note, Total"},
+		{`<inv:IssueDate>`, `<inv:Foo/><inv:IssueDate>`, "/Invoice/Foo", "unexpected element Foo. Expected IssueDate"},
+		{`<inv:Name>ACME &amp; Co</inv:Name>`, ``, "/Invoice/Customer", "missing element Name"},
+		{`<inv:Qty>1</inv:Qty>`, `<inv:Qty>1</inv:Qty>text`, "/Invoice/Line", "text not allowed"},
+		{` n="1"`, ` n="1" x="y"`, "/Invoice/Line", "attribute x not allowed"},
+	} {
+		doc := strings.Replace(validInvoice, tc.old, tc.new, 1)
+		err := validate(s, doc)
+		errs, ok := err.(Errors)
+		if !ok {
+			t.Fatalf("Expected Errors replacing %s. Got %v", tc.old, err)
+		}
+		if errs[0].Path != tc.path || !strings.Contains(errs[0].Msg, tc.msg) {
+			t.Fatalf("Unexpected error replacing %s: %v. Expected %s: %s", tc.old, errs, tc.path, tc.msg)
+		}
+		if errs[0].Offset <= 0 || errs[0].Offset >= int64(len(doc)) {
+			t.Fatalf("Unexpected offset %d", errs[0].Offset)
+		}
+	}
+}
+
+func TestValidateIncremental(t *testing.T) {
+	s := loadInvoice(t)
+
+	v := s.NewValidator()
+	r := xml.NewReader(strings.NewReader(strings.Replace(validInvoice, "INV-0042", "INV-42", 1)))
+	lines := 0
+	for r.Next() {
+		v.Feed(r.Element(), r.Offset())
+		if e, ok := r.Element().(*xml.StartElement); ok && e.Name() == "inv:Line" {
+			lines++
+		}
+	}
+	if lines != 2 {
+		t.Fatalf("Unexpected lines: %d", lines)
+	}
+	if err := v.Close(); err == nil || len(v.Errors()) != 1 {
+		t.Fatalf("Unexpected errors: %v", v.Errors())
+	}
+}
+
+func TestValidateNotClosed(t *testing.T) {
+	s := loadInvoice(t)
+
+	doc := strings.TrimSuffix(validInvoice, "</inv:Invoice>")
+	errs, ok := validate(s, doc).(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if errs[0].Path != "/Invoice" || errs[0].Msg != "element not closed" ||
+		errs[0].Offset != int64(strings.Index(doc, "<inv:Invoice")) {
+		t.Fatalf("Unexpected error: %v", errs[0])
+	}
+}
+
+func TestLoadOccurrences(t *testing.T) {
+	const xsd = `<schema xmlns="http://www.w3.org/2001/XMLSchema">
+  <element name="list">
+    <complexType>
+      <sequence minOccurs="2" maxOccurs="3">
+        <element name="item" type="int"/>
+      </sequence>
+    </complexType>
+  </element>
+</schema>`
+
+	s, err := Load(strings.NewReader(xsd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, valid := range map[int]bool{1: false, 2: true, 3: true, 4: false} {
+		doc := "<list>" + strings.Repeat("<item>-7</item>", n) + "</list>"
+		if err := validate(s, doc); (err == nil) != valid {
+			t.Fatalf("Unexpected result with %d items: %v", n, err)
+		}
+	}
+}
+
+func TestLoadUnsupportedPattern(t *testing.T) {
+	const xsd = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="root">
+    <xs:simpleType>
+      <xs:restriction base="xs:string">
+        <xs:pattern value="[\I0-9]+"/>
+      </xs:restriction>
+    </xs:simpleType>
+  </xs:element>
+</xs:schema>`
+
+	_, err := Load(strings.NewReader(xsd))
+	if err == nil || !strings.Contains(err.Error(), "unsupported pattern") {
+		t.Fatalf("Expected an unsupported pattern error. Got %v", err)
+	}
+}
+
+func TestSimpleTypes(t *testing.T) {
+	const xsd = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="root">
+    <xs:complexType mixed="true">
+      <xs:sequence>
+        <xs:element name="sizes" type="sizes"/>
+        <xs:element name="when" type="when"/>
+        <xs:any processContents="skip" minOccurs="0"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:simpleType name="sizes">
+    <xs:restriction>
+      <xs:simpleType>
+        <xs:list itemType="xs:unsignedByte"/>
+      </xs:simpleType>
+      <xs:maxLength value="3"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="when">
+    <xs:union memberTypes="xs:dateTime">
+      <xs:simpleType>
+        <xs:restriction base="xs:token">
+          <xs:pattern value="now|\i\c*\$"/>
+        </xs:restriction>
+      </xs:simpleType>
+    </xs:union>
+  </xs:simpleType>
+</xs:schema>`
+
+	s, err := Load(strings.NewReader(xsd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for doc, valid := range map[string]bool{
+		`<root>text<sizes> 1 2
+ 255 </sizes><when>2020-01-01T10:00:00Z</when><x:y a="b"><z/></x:y></root>`: true,
+		`<root><sizes>1 2 3 4</sizes><when>now</when></root>`:           false,
+		`<root><sizes>256</sizes><when>now</when></root>`:               false,
+		`<root><sizes>1</sizes><when>later</when></root>`:               false,
+		`<root><sizes>1</sizes><when>_later$</when></root>`:             true,
+		`<root><sizes>1</sizes><when>2020-13-01T10:00:00</when></root>`: false,
+	} {
+		if err := validate(s, doc); (err == nil) != valid {
+			t.Fatalf("Unexpected result validating %s: %v", doc, err)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package schema
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type whitespace uint8
+
+const (
+	wsPreserve whitespace = iota
+	wsReplace
+	wsCollapse
+)
+
+type variety uint8
+
+const (
+	atomicVariety variety = iota
+	listVariety
+	unionVariety
+)
+
+// bound is a minInclusive, maxInclusive, minExclusive or maxExclusive facet.
+type bound struct {
+	value     string
+	exclusive int // 1 if the bound is exclusive.
+	min       bool
+}
+
+// simpleType is a simple type definition.
+//
+// Built-in types have check set. Derived types have base set
+// and they only hold the facets added by the derivation.
+type simpleType struct {
+	name    string
+	base    *simpleType
+	variety variety
+	item    *simpleType   // list item type.
+	members []*simpleType // union member types.
+	ws      whitespace
+
+	// built-in types
+	check    func(v string) error
+	compare  func(a, b string) (int, error)
+	lengthOf func(v string) int
+
+	enums          []string
+	pattern        *regexp.Regexp
+	length         int
+	minLength      int
+	maxLength      int
+	totalDigits    int
+	fractionDigits int
+	bounds         []bound
+}
+
+// validate checks v against the type.
+func (t *simpleType) validate(v string) error {
+	return t.validateNormalized(normalize(v, t.ws))
+}
+
+func (t *simpleType) validateNormalized(v string) error {
+	switch {
+	case t.check != nil:
+		if err := t.check(v); err != nil {
+			return err
+		}
+	case t.base != nil:
+		if err := t.base.validateNormalized(v); err != nil {
+			return err
+		}
+	case t.variety == listVariety:
+		for _, item := range strings.Fields(v) {
+			if err := t.item.validate(item); err != nil {
+				return err
+			}
+		}
+	case t.variety == unionVariety:
+		var err error
+		for _, m := range t.members {
+			if err = m.validate(v); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%q doesn't match any member of the union", v)
+		}
+	}
+
+	return t.checkFacets(v)
+}
+
+func (t *simpleType) checkFacets(v string) error {
+	if t.pattern != nil && !t.pattern.MatchString(v) {
+		return fmt.Errorf("%q doesn't match the pattern %s", v, t.pattern)
+	}
+
+	if len(t.enums) > 0 {
+		found := false
+		for _, e := range t.enums {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%q is not one of %s", v, strings.Join(t.enums, ", "))
+		}
+	}
+
+	if t.length >= 0 || t.minLength >= 0 || t.maxLength >= 0 {
+		n := t.valueLength(v)
+		switch {
+		case t.length >= 0 && n != t.length:
+			return fmt.Errorf("%q length must be %d", v, t.length)
+		case t.minLength >= 0 && n < t.minLength:
+			return fmt.Errorf("%q length must be at least %d", v, t.minLength)
+		case t.maxLength >= 0 && n > t.maxLength:
+			return fmt.Errorf("%q length must be at most %d", v, t.maxLength)
+		}
+	}
+
+	if t.totalDigits >= 0 || t.fractionDigits >= 0 {
+		total, fraction := countDigits(v)
+		switch {
+		case t.totalDigits >= 0 && total > t.totalDigits:
+			return fmt.Errorf("%q has more than %d digits", v, t.totalDigits)
+		case t.fractionDigits >= 0 && fraction > t.fractionDigits:
+			return fmt.Errorf("%q has more than %d fraction digits", v, t.fractionDigits)
+		}
+	}
+
+	if len(t.bounds) > 0 {
+		cmp := t.comparer()
+		if cmp == nil {
+			return nil
+		}
+		for _, b := range t.bounds {
+			c, err := cmp(v, b.value)
+			if err != nil {
+				return err
+			}
+			if !b.min {
+				c = -c
+			}
+			// c is the distance to the bound in the direction it allows.
+			if c < b.exclusive {
+				op := map[bool]string{true: ">", false: "<"}[b.min]
+				if b.exclusive == 0 {
+					op += "="
+				}
+				return fmt.Errorf("%q must be %s %s", v, op, b.value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// comparer returns the compare function of the primitive type.
+func (t *simpleType) comparer() func(a, b string) (int, error) {
+	for ; t != nil; t = t.base {
+		if t.compare != nil {
+			return t.compare
+		}
+	}
+	return nil
+}
+
+func (t *simpleType) valueLength(v string) int {
+	for p := t; p != nil; p = p.base {
+		if p.variety == listVariety {
+			return len(strings.Fields(v))
+		}
+		if p.lengthOf != nil {
+			return p.lengthOf(v)
+		}
+	}
+	return utf8.RuneCountInString(v)
+}
+
+// normalize applies the whitespace facet to v.
+func normalize(v string, ws whitespace) string {
+	switch ws {
+	case wsReplace:
+		return strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' || r == '\r' {
+				return ' '
+			}
+			return r
+		}, v)
+	case wsCollapse:
+		if strings.IndexAny(v, "\t\n\r") < 0 && !strings.Contains(v, "  ") &&
+			strings.TrimSpace(v) == v {
+			return v
+		}
+		return strings.Join(strings.Fields(v), " ")
+	}
+	return v
+}
+
+func countDigits(v string) (total, fraction int) {
+	v = strings.TrimLeft(v, "+-")
+	i := strings.IndexByte(v, '.')
+	if i >= 0 {
+		v = strings.TrimRight(v, "0")
+		fraction = len(v) - i - 1
+		v = v[:i] + v[i+1:]
+	}
+	v = strings.TrimLeft(v, "0")
+	return len(v), fraction
+}
+
+// newSimple creates a simpleType without facets.
+func newSimple(v variety, ws whitespace) *simpleType {
+	return &simpleType{
+		variety:        v,
+		ws:             ws,
+		length:         -1,
+		minLength:      -1,
+		maxLength:      -1,
+		totalDigits:    -1,
+		fractionDigits: -1,
+	}
+}
+
+func newBuiltin(name string, ws whitespace, check func(string) error) *simpleType {
+	t := newSimple(atomicVariety, ws)
+	t.name, t.check = name, check
+	return t
+}
+
+func matcher(name, expr string) func(string) error {
+	re := regexp.MustCompile(`^(?:` + expr + `)$`)
+	return func(v string) error {
+		if !re.MatchString(v) {
+			return fmt.Errorf("%q is not a valid %s", v, name)
+		}
+		return nil
+	}
+}
+
+const (
+	ncNameExpr = `[\p{L}_][\p{L}\p{N}\p{M}._\-]*`
+	nameExpr   = `[\p{L}_:][\p{L}\p{N}\p{M}._\-:]*`
+	tzExpr     = `(?:Z|[+-](?:(?:0\d|1[0-3]):[0-5]\d|14:00))?`
+	yearExpr   = `-?(?:[1-9]\d{4,}|\d{4})`
+	timeExpr   = `(?:(?:[01]\d|2[0-3]):[0-5]\d:[0-5]\d(?:\.\d+)?|24:00:00(?:\.0+)?)`
+)
+
+// builtins holds the built-in datatypes by name.
+var builtins = map[string]*simpleType{}
+
+func init() {
+	add := func(t *simpleType) *simpleType {
+		builtins[t.name] = t
+		return t
+	}
+	anything := func(string) error { return nil }
+
+	add(newBuiltin("anySimpleType", wsPreserve, anything))
+	add(newBuiltin("string", wsPreserve, anything))
+	add(newBuiltin("normalizedString", wsReplace, anything))
+	add(newBuiltin("token", wsCollapse, anything))
+	add(newBuiltin("anyURI", wsCollapse, anything))
+	add(newBuiltin("language", wsCollapse, matcher("language", `[a-zA-Z]{1,8}(?:-[a-zA-Z0-9]{1,8})*`)))
+	add(newBuiltin("Name", wsCollapse, matcher("Name", nameExpr)))
+	add(newBuiltin("NMTOKEN", wsCollapse, matcher("NMTOKEN", `[\p{L}\p{N}\p{M}._\-:]+`)))
+	add(newBuiltin("NMTOKENS", wsCollapse, matcher("NMTOKENS", `[\p{L}\p{N}\p{M}._\-:]+(?: [\p{L}\p{N}\p{M}._\-:]+)*`)))
+	for _, name := range []string{"NCName", "ID", "IDREF", "ENTITY"} {
+		add(newBuiltin(name, wsCollapse, matcher(name, ncNameExpr)))
+	}
+	for _, name := range []string{"IDREFS", "ENTITIES"} {
+		add(newBuiltin(name, wsCollapse, matcher(name, ncNameExpr+`(?: `+ncNameExpr+`)*`)))
+	}
+	add(newBuiltin("QName", wsCollapse, matcher("QName", `(?:`+ncNameExpr+`:)?`+ncNameExpr)))
+	add(newBuiltin("NOTATION", wsCollapse, matcher("NOTATION", `(?:`+ncNameExpr+`:)?`+ncNameExpr)))
+
+	add(newBuiltin("boolean", wsCollapse, matcher("boolean", `true|false|1|0`)))
+
+	decimal := add(newBuiltin("decimal", wsCollapse, matcher("decimal", `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`)))
+	decimal.compare = compareDecimal
+
+	integers := []struct {
+		name     string
+		min, max string
+	}{
+		{"integer", "", ""},
+		{"nonPositiveInteger", "", "0"},
+		{"negativeInteger", "", "-1"},
+		{"nonNegativeInteger", "0", ""},
+		{"positiveInteger", "1", ""},
+		{"long", "-9223372036854775808", "9223372036854775807"},
+		{"int", "-2147483648", "2147483647"},
+		{"short", "-32768", "32767"},
+		{"byte", "-128", "127"},
+		{"unsignedLong", "0", "18446744073709551615"},
+		{"unsignedInt", "0", "4294967295"},
+		{"unsignedShort", "0", "65535"},
+		{"unsignedByte", "0", "255"},
+	}
+	for _, it := range integers {
+		t := add(newBuiltin(it.name, wsCollapse, checkInteger(it.name, it.min, it.max)))
+		t.compare = compareDecimal
+	}
+
+	for _, name := range []string{"float", "double"} {
+		t := add(newBuiltin(name, wsCollapse,
+			matcher(name, `[+-]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?|[+-]?INF|NaN`)))
+		t.compare = compareFloat
+	}
+
+	add(newBuiltin("duration", wsCollapse, checkDuration))
+
+	dateTime := add(newBuiltin("dateTime", wsCollapse, checkDate("dateTime",
+		yearExpr+`-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])T`+timeExpr+tzExpr, "2006-01-02T15:04:05")))
+	dateTime.compare = compareTime("2006-01-02T15:04:05")
+
+	date := add(newBuiltin("date", wsCollapse, checkDate("date",
+		yearExpr+`-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])`+tzExpr, "2006-01-02")))
+	date.compare = compareTime("2006-01-02")
+
+	tm := add(newBuiltin("time", wsCollapse, matcher("time", timeExpr+tzExpr)))
+	tm.compare = compareTime("15:04:05")
+
+	add(newBuiltin("gYear", wsCollapse, matcher("gYear", yearExpr+tzExpr)))
+	add(newBuiltin("gYearMonth", wsCollapse, matcher("gYearMonth", yearExpr+`-(?:0[1-9]|1[0-2])`+tzExpr)))
+	add(newBuiltin("gMonth", wsCollapse, matcher("gMonth", `--(?:0[1-9]|1[0-2])`+tzExpr)))
+	add(newBuiltin("gMonthDay", wsCollapse, matcher("gMonthDay", `--(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])`+tzExpr)))
+	add(newBuiltin("gDay", wsCollapse, matcher("gDay", `---(?:0[1-9]|[12]\d|3[01])`+tzExpr)))
+
+	hex := add(newBuiltin("hexBinary", wsCollapse, matcher("hexBinary", `(?:[0-9a-fA-F]{2})*`)))
+	hex.lengthOf = func(v string) int { return len(v) / 2 }
+
+	b64 := add(newBuiltin("base64Binary", wsCollapse, func(v string) error {
+		if _, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(v, " ", "")); err != nil {
+			return fmt.Errorf("%q is not a valid base64Binary", v)
+		}
+		return nil
+	}))
+	b64.lengthOf = func(v string) int {
+		b, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(v, " ", ""))
+		return len(b)
+	}
+}
+
+var integerRe = regexp.MustCompile(`^[+-]?\d+$`)
+
+func checkInteger(name, min, max string) func(string) error {
+	var lo, hi *big.Int
+	if min != "" {
+		lo, _ = new(big.Int).SetString(min, 10)
+	}
+	if max != "" {
+		hi, _ = new(big.Int).SetString(max, 10)
+	}
+
+	return func(v string) error {
+		if !integerRe.MatchString(v) {
+			return fmt.Errorf("%q is not a valid %s", v, name)
+		}
+		if lo == nil && hi == nil {
+			return nil
+		}
+		n, _ := new(big.Int).SetString(strings.TrimPrefix(v, "+"), 10)
+		if (lo != nil && n.Cmp(lo) < 0) || (hi != nil && n.Cmp(hi) > 0) {
+			return fmt.Errorf("%q is out of the %s range", v, name)
+		}
+		return nil
+	}
+}
+
+var durationRe = regexp.MustCompile(`^-?P(?:\d+Y)?(?:\d+M)?(?:\d+D)?(?:T(?:\d+H)?(?:\d+M)?(?:\d+(?:\.\d+)?S)?)?$`)
+
+func checkDuration(v string) error {
+	if !durationRe.MatchString(v) || strings.HasSuffix(v, "P") || strings.HasSuffix(v, "T") {
+		return fmt.Errorf("%q is not a valid duration", v)
+	}
+	return nil
+}
+
+// checkDate checks the lexical form of v with expr and the validity
+// of the date parsing its first len(layout) bytes.
+func checkDate(name, expr, layout string) func(string) error {
+	match := matcher(name, expr)
+	return func(v string) error {
+		if err := match(v); err != nil {
+			return err
+		}
+		if strings.HasPrefix(v, "-") || len(v) < len(layout) || v[4] != '-' {
+			return nil // years out of the time.Parse range.
+		}
+		s := v[:len(layout)]
+		if strings.HasSuffix(s, "T24:00:00") {
+			s = s[:len(s)-8] + "00:00:00"
+		}
+		if _, err := time.Parse(layout, s); err != nil {
+			return fmt.Errorf("%q is not a valid %s", v, name)
+		}
+		return nil
+	}
+}
+
+func compareDecimal(a, b string) (int, error) {
+	x, ok := new(big.Rat).SetString(strings.TrimPrefix(a, "+"))
+	if !ok {
+		return 0, fmt.Errorf("%q is not a number", a)
+	}
+	y, ok := new(big.Rat).SetString(strings.TrimPrefix(b, "+"))
+	if !ok {
+		return 0, fmt.Errorf("invalid facet value %q", b)
+	}
+	return x.Cmp(y), nil
+}
+
+func parseXSDFloat(v string) (float64, error) {
+	switch v {
+	case "INF", "+INF":
+		v = "+Inf"
+	case "-INF":
+		v = "-Inf"
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func compareFloat(a, b string) (int, error) {
+	x, err := parseXSDFloat(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := parseXSDFloat(b)
+	if err != nil {
+		return 0, fmt.Errorf("invalid facet value %q", b)
+	}
+	switch {
+	case x < y:
+		return -1, nil
+	case x > y:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func compareTime(layout string) func(a, b string) (int, error) {
+	parse := func(v string) (time.Time, error) {
+		for _, l := range []string{layout + "Z07:00", layout} {
+			if t, err := time.Parse(l, v); err == nil {
+				return t, nil
+			}
+			if t, err := time.Parse(l+".999999999Z07:00", v); err == nil {
+				return t, nil
+			}
+			if t, err := time.Parse(l+".999999999", v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not comparable", v)
+	}
+	return func(a, b string) (int, error) {
+		x, err := parse(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := parse(b)
+		if err != nil {
+			return 0, err
+		}
+		return x.Compare(y), nil
+	}
+}
+
+// compilePattern compiles the XML Schema regular expressions in patterns.
+//
+// The patterns of the same derivation step are alternatives.
+func compilePattern(patterns []string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^(?:")
+	for i, p := range patterns {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		expr, err := translatePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("schema: unsupported pattern %q: %w", p, err)
+		}
+		sb.WriteString("(?:")
+		sb.WriteString(expr)
+		sb.WriteByte(')')
+	}
+	sb.WriteString(")$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("schema: unsupported pattern %q: %w", strings.Join(patterns, "|"), err)
+	}
+	return re, nil
+}
+
+// translatePattern translates an XML Schema regular expression into Go's syntax.
+//
+// XML Schema expressions are implicitly anchored, `^` and `$` are
+// not special and there are the \i and \c multi-character escapes.
+// Their negations, \I and \C, are not supported inside a character class.
+func translatePattern(p string) (string, error) {
+	const (
+		initial = `\p{L}_:`
+		name    = `\p{L}\p{N}\p{M}._\-:`
+	)
+
+	var sb strings.Builder
+	inClass := false
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '\\' && i+1 < len(p):
+			i++
+			var class string
+			switch p[i] {
+			case 'i':
+				class = initial
+			case 'c':
+				class = name
+			case 'I', 'C':
+				neg := initial
+				if p[i] == 'C' {
+					neg = name
+				}
+				if inClass { // Go can't subtract a class inside another one
+					return "", fmt.Errorf(`\%c inside a character class`, p[i])
+				}
+				sb.WriteString(`[^` + neg + `]`)
+				continue
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(p[i])
+				continue
+			}
+			if inClass {
+				sb.WriteString(class)
+			} else {
+				sb.WriteString(`[` + class + `]`)
+			}
+		case c == '[':
+			inClass = true
+			sb.WriteByte(c)
+			if i+1 < len(p) && p[i+1] == '^' {
+				sb.WriteByte('^')
+				i++
+			}
+		case c == ']':
+			inClass = false
+			sb.WriteByte(c)
+		case (c == '^' || c == '$') && !inClass:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
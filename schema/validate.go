@@ -0,0 +1,420 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	xml "github.com/dgrr/quickxml"
+)
+
+// Error is a validation error.
+type Error struct {
+	// Path is the path of the element with the error, like /Invoice/Line/Amount.
+	Path string
+	// Offset is the position of the element in the input.
+	Offset int64
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (offset %d): %s", e.Path, e.Offset, e.Msg)
+}
+
+// Errors are the errors found validating a document.
+type Errors []*Error
+
+func (es Errors) Error() string {
+	switch len(es) {
+	case 0:
+		return "no errors"
+	case 1:
+		return es[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", es[0], len(es)-1)
+}
+
+// frame is the validation state of an open element.
+type frame struct {
+	elem    *element
+	ct      *complexType
+	simple  *simpleType
+	any     bool // xs:anyType. Anything is allowed.
+	skip    bool // the element is not validated.
+	nilled  bool
+	hasText bool // the element has non whitespace text.
+
+	set    []int // states of the content model.
+	spare  []int
+	counts []int // occurrences of the elements of an all group.
+	text   []byte
+
+	pathLen int
+	offset  int64 // position of the StartElement.
+}
+
+// Validator validates a document incrementally.
+//
+// The elements must be passed to Feed in the order they are read.
+type Validator struct {
+	// MaxErrors is the number of errors after which the validator
+	// stops collecting them. Zero means no limit.
+	MaxErrors int
+
+	s      *Schema
+	frames []frame
+	path   []byte
+	errs   Errors
+	root   bool
+}
+
+// NewValidator creates a Validator for s.
+func (s *Schema) NewValidator() *Validator {
+	return &Validator{s: s}
+}
+
+// Validate validates the document read by r.
+//
+// It returns the error of the reader if reading fails,
+// or Errors if the document is not valid.
+func (s *Schema) Validate(r *xml.Reader) error {
+	v := s.NewValidator()
+	for r.Next() {
+		v.Feed(r.Element(), r.Offset())
+	}
+	if err := r.Error(); err != nil && err != io.EOF {
+		return err
+	}
+	return v.Close()
+}
+
+// Feed validates e, which starts at offset in the input (see xml.Reader.Offset).
+func (v *Validator) Feed(e xml.Element, offset int64) {
+	switch e := e.(type) {
+	case *xml.StartElement:
+		v.start(e, offset)
+		if e.HasEnd() {
+			v.end(offset)
+		}
+	case *xml.EndElement:
+		v.end(offset)
+	case *xml.TextElement:
+		v.text(e, offset)
+	}
+}
+
+// Close finishes the validation returning the Errors found, if any.
+func (v *Validator) Close() error {
+	if !v.root {
+		v.errorf(0, "empty document")
+	}
+	for len(v.frames) > 0 {
+		v.errorf(v.frames[len(v.frames)-1].offset, "element not closed")
+		v.pop()
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// Errors returns the errors found so far.
+func (v *Validator) Errors() Errors {
+	return v.errs
+}
+
+func (v *Validator) errorf(offset int64, format string, args ...interface{}) {
+	if v.MaxErrors > 0 && len(v.errs) >= v.MaxErrors {
+		return
+	}
+	path := string(v.path)
+	if path == "" {
+		path = "/"
+	}
+	v.errs = append(v.errs, &Error{
+		Path:   path,
+		Offset: offset,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *Validator) push(name string) *frame {
+	n := len(v.frames)
+	if n < cap(v.frames) {
+		v.frames = v.frames[:n+1]
+	} else {
+		v.frames = append(v.frames, frame{})
+	}
+
+	f := &v.frames[n]
+	*f = frame{
+		set:     f.set[:0],
+		spare:   f.spare[:0],
+		counts:  f.counts[:0],
+		text:    f.text[:0],
+		pathLen: len(v.path),
+	}
+	v.path = append(append(v.path, '/'), name...)
+	return f
+}
+
+func (v *Validator) pop() {
+	f := &v.frames[len(v.frames)-1]
+	v.path = v.path[:f.pathLen]
+	v.frames = v.frames[:len(v.frames)-1]
+}
+
+func (v *Validator) start(e *xml.StartElement, offset int64) {
+	name := localName(e.NameUnsafe())
+
+	var parent *frame
+	if n := len(v.frames); n > 0 {
+		parent = &v.frames[n-1]
+	}
+
+	f := v.push(name)
+	f.offset = offset
+	if parent == nil {
+		if v.root {
+			v.errorf(offset, "multiple root elements")
+			f.skip = true
+			return
+		}
+		v.root = true
+		if f.elem = v.s.elements[name]; f.elem == nil {
+			v.errorf(offset, "unknown root element %s", name)
+			f.skip = true
+			return
+		}
+	} else {
+		// the push might have moved the frames
+		parent = &v.frames[len(v.frames)-2]
+		if f.elem, f.skip = v.child(parent, name, offset); f.skip {
+			return
+		}
+	}
+
+	v.setType(f, e, offset)
+}
+
+// child returns the declaration of the child element name of parent.
+func (v *Validator) child(parent *frame, name string, offset int64) (*element, bool) {
+	switch {
+	case parent.skip, parent.any:
+		return nil, true
+	case parent.nilled:
+		v.errorf(offset, "nil element can't have children")
+		return nil, true
+	case parent.ct == nil || parent.ct.simple != nil:
+		v.errorf(offset, "element %s not allowed in simple content", name)
+		return nil, true
+	}
+
+	cm := parent.ct.content
+	if cm == nil {
+		v.errorf(offset, "element %s not allowed in empty content", name)
+		return nil, true
+	}
+
+	var p *particle
+	if cm.all != nil {
+		for i, c := range cm.all {
+			if c.kind == elementParticle && c.elem.name == name {
+				parent.counts[i]++
+				if parent.counts[i] > 1 {
+					v.errorf(offset, "element %s repeated", name)
+				}
+				p = c
+				break
+			}
+		}
+	} else {
+		parent.spare, p = cm.step(parent.spare[:0], parent.set, name)
+		if p != nil {
+			parent.set, parent.spare = parent.spare, parent.set
+		}
+	}
+
+	if p == nil {
+		expected := "no more elements"
+		if cm.all == nil {
+			expected = cm.expected(parent.set)
+		}
+		v.errorf(offset, "unexpected element %s. Expected %s", name, expected)
+		return nil, true
+	}
+
+	if p.kind == anyParticle {
+		if p.skip {
+			return nil, true
+		}
+		e := v.s.elements[name]
+		return e, e == nil
+	}
+	return p.elem, false
+}
+
+// setType prepares f to validate the content of e.
+func (v *Validator) setType(f *frame, e *xml.StartElement, offset int64) {
+	decl := f.elem
+	if decl.typ == nil || decl.typ == anyType {
+		f.any = true
+		return
+	}
+
+	f.simple = decl.typ.simple
+	if ct := decl.typ.complex; ct != nil {
+		f.ct = ct
+		if ct.simple != nil {
+			f.simple = ct.simple
+		}
+		if cm := ct.content; cm != nil {
+			if cm.all != nil {
+				for range cm.all {
+					f.counts = append(f.counts, 0)
+				}
+			} else {
+				f.set = cm.closure(f.set, cm.start)
+			}
+		}
+	}
+
+	v.checkAttrs(f, e, offset)
+}
+
+func (v *Validator) checkAttrs(f *frame, e *xml.StartElement, offset int64) {
+	var buf []byte
+
+	e.Attrs().Range(func(kv *xml.KV) {
+		key := kv.KeyUnsafe()
+		if key == "xmlns" || strings.HasPrefix(key, "xmlns:") || strings.HasPrefix(key, "xml:") {
+			return
+		}
+		buf = xml.Unescape(buf[:0], kv.ValueBytes())
+		value := string(buf)
+
+		if strings.HasPrefix(key, "xsi:") {
+			if key == "xsi:nil" && strings.TrimSpace(value) == "true" {
+				if !f.elem.nillable {
+					v.errorf(offset, "element is not nillable")
+				}
+				f.nilled = true
+			}
+			return
+		}
+
+		name := localName(key)
+		var a *attribute
+		if f.ct != nil {
+			for _, ca := range f.ct.attrs {
+				if ca.name == name {
+					a = ca
+					break
+				}
+			}
+		}
+		if a == nil || a.prohibited {
+			if f.ct == nil || !f.ct.anyAttr {
+				v.errorf(offset, "attribute %s not allowed", name)
+			}
+			return
+		}
+
+		if a.typ != nil {
+			if err := a.typ.validate(value); err != nil {
+				v.errorf(offset, "attribute %s: %s", name, err)
+				return
+			}
+		}
+		if a.hasFixed && value != a.fixed {
+			v.errorf(offset, "attribute %s must be %q", name, a.fixed)
+		}
+	})
+
+	if f.ct == nil {
+		return
+	}
+	for _, a := range f.ct.attrs {
+		if !a.required {
+			continue
+		}
+		found := false
+		e.Attrs().RangePre(func(kv *xml.KV) bool {
+			found = localName(kv.KeyUnsafe()) == a.name
+			return !found
+		})
+		if !found {
+			v.errorf(offset, "missing required attribute %s", a.name)
+		}
+	}
+}
+
+func (v *Validator) text(e *xml.TextElement, offset int64) {
+	if len(v.frames) == 0 {
+		if strings.TrimSpace(e.String()) != "" {
+			v.errorf(offset, "text outside the root element")
+		}
+		return
+	}
+
+	f := &v.frames[len(v.frames)-1]
+	if f.skip || f.any {
+		return
+	}
+
+	blank := strings.TrimSpace(e.String()) == ""
+	switch {
+	case f.nilled:
+		if !blank {
+			v.errorf(offset, "nil element can't have text")
+		}
+	case f.simple != nil:
+		f.text = xml.Unescape(f.text, []byte(e.String()))
+	case !blank && !f.hasText && (f.ct == nil || !f.ct.mixed):
+		f.hasText = true
+		v.errorf(offset, "text not allowed in element-only content")
+	}
+}
+
+func (v *Validator) end(offset int64) {
+	if len(v.frames) == 0 {
+		return
+	}
+
+	f := &v.frames[len(v.frames)-1]
+	if !f.skip && !f.any && !f.nilled {
+		v.checkContent(f, offset)
+	}
+	v.pop()
+}
+
+func (v *Validator) checkContent(f *frame, offset int64) {
+	if f.simple != nil {
+		text := string(f.text)
+		if err := f.simple.validate(text); err != nil {
+			v.errorf(offset, "%s", err)
+		} else if f.elem.hasFixed && normalize(text, f.simple.ws) != f.elem.fixed {
+			v.errorf(offset, "value must be %q", f.elem.fixed)
+		}
+		return
+	}
+
+	if f.ct == nil || f.ct.content == nil {
+		return
+	}
+
+	cm := f.ct.content
+	if cm.all != nil {
+		for i, p := range cm.all {
+			if f.counts[i] < p.min && p.kind == elementParticle {
+				v.errorf(offset, "missing element %s", p.elem.name)
+			}
+		}
+		return
+	}
+
+	if !cm.accepts(f.set) {
+		v.errorf(offset, "incomplete content. Expected %s", cm.expected(f.set))
+	}
+}
@@ -1,10 +1,11 @@
 package xml
 
 import (
-	"bufio"
 	"bytes"
-	"fmt"
+	"io"
+	"sort"
 	"sync"
+	"time"
 )
 
 var startPool = sync.Pool{
@@ -45,14 +46,17 @@ func NewAttrs(attrs ...string) *Attrs {
 
 // CopyTo copies kvs to kv2.
 func (kvs *Attrs) CopyTo(kv2 *Attrs) {
+	g := kv2.gen()
 	if n := len(*kvs) - len(*kv2); n > 0 {
 		*kv2 = append(*kv2, make([]KV, n)...)
 	}
+	*kv2 = (*kv2)[:len(*kvs)]
 
 	kvs.RangeWithIndex(func(i int, kv *KV) {
 		(*kv2)[i].k = append((*kv2)[i].k[:0], kv.k...)
 		(*kv2)[i].v = append((*kv2)[i].v[:0], kv.v...)
 	})
+	kv2.touch(g)
 }
 
 // Len returns the number of attributes.
@@ -62,32 +66,239 @@ func (kvs *Attrs) Len() int {
 
 // Get returns the attribute based on name.
 //
+// The returned KV points to the attribute inside kvs,
+// so modifying it modifies the attribute list.
+//
 // If the name doesn't match any of the keys KV will be nil.
 func (kvs *Attrs) Get(name string) *KV {
-	for _, kv := range *kvs {
-		if kv.KeyUnsafe() == name {
-			return &kv
-		}
-	}
-	return nil
+	return kvs.lookup(name)
 }
 
 // GetBytes returns the attribute based on name.
 //
 // If the name doesn't match any of the keys KV will be nil.
 func (kvs *Attrs) GetBytes(name []byte) *KV {
-	for _, kv := range *kvs {
-		if bytes.Equal(kv.KeyBytes(), name) {
-			return &kv
+	return kvs.lookup(b2s(name))
+}
+
+// Set sets the value of the attribute name.
+//
+// If the attribute doesn't exist it is added at the end of the list.
+func (kvs *Attrs) Set(name, value string) {
+	if kv := kvs.lookup(name); kv != nil {
+		kv.v = append(kv.v[:0], value...)
+	} else {
+		kvs.Add(name, value)
+	}
+}
+
+// SetBytes sets the value of the attribute name.
+//
+// If the attribute doesn't exist it is added at the end of the list.
+func (kvs *Attrs) SetBytes(name, value []byte) {
+	if kv := kvs.lookup(b2s(name)); kv != nil {
+		kv.v = append(kv.v[:0], value...)
+	} else {
+		kvs.AddBytes(name, value)
+	}
+}
+
+// Add appends the attribute name=value to the list.
+//
+// Add doesn't check whether the attribute already exists. Use Set for that.
+func (kvs *Attrs) Add(name, value string) {
+	g := kvs.gen()
+	kv := kvs.next()
+	kv.k = append(kv.k[:0], name...)
+	kv.v = append(kv.v[:0], value...)
+	kvs.touch(g)
+}
+
+// AddBytes appends the attribute name=value to the list.
+//
+// AddBytes doesn't check whether the attribute already exists. Use SetBytes for that.
+func (kvs *Attrs) AddBytes(name, value []byte) {
+	g := kvs.gen()
+	kv := kvs.next()
+	kv.k = append(kv.k[:0], name...)
+	kv.v = append(kv.v[:0], value...)
+	kvs.touch(g)
+}
+
+// Delete removes the attribute name keeping the order of the rest.
+//
+// Delete returns false if the attribute doesn't exist.
+func (kvs *Attrs) Delete(name string) bool {
+	for i := range *kvs {
+		if (*kvs)[i].KeyUnsafe() == name {
+			kvs.remove(i)
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteBytes removes the attribute name keeping the order of the rest.
+//
+// DeleteBytes returns false if the attribute doesn't exist.
+func (kvs *Attrs) DeleteBytes(name []byte) bool {
+	return kvs.Delete(b2s(name))
+}
+
+// Sort sorts the attributes by key.
+func (kvs *Attrs) Sort() {
+	g := kvs.gen()
+	sort.Sort(kvsByKey(*kvs))
+	kvs.touch(g)
+}
+
+// next extends the list by one reusing the buffers
+// of previously removed attributes when possible.
+func (kvs *Attrs) next() *KV {
+	n := len(*kvs)
+	if n < cap(*kvs) {
+		*kvs = (*kvs)[:n+1]
+	} else {
+		*kvs = append(*kvs, KV{})
+	}
+	return &(*kvs)[n]
+}
+
+// remove deletes the attribute at i moving it
+// past the end of the list so its buffers can be reused.
+func (kvs *Attrs) remove(i int) {
+	g := kvs.gen()
+	a := *kvs
+	kv := a[i]
+	copy(a[i:], a[i+1:])
+	a[len(a)-1] = kv
+	*kvs = a[:len(a)-1]
+	kvs.touch(g)
+}
+
+// gen returns the generation of the list, which changes
+// every time an attribute is added, removed or moved.
+// It lets the index of a StartElement know when it has to be rebuilt.
+func (kvs *Attrs) gen() uint32 {
+	if len(*kvs) == 0 {
+		return 0
+	}
+	return (*kvs)[0].gen
+}
+
+// touch sets the generation following g to the list just changed.
+//
+// The generation is kept in the first attribute, so it is set again
+// after the attributes have been moved.
+func (kvs *Attrs) touch(g uint32) {
+	if len(*kvs) > 0 {
+		(*kvs)[0].gen = g + 1
+	}
+}
+
+type kvsByKey Attrs
+
+func (a kvsByKey) Len() int           { return len(a) }
+func (a kvsByKey) Less(i, j int) bool { return bytes.Compare(a[i].k, a[j].k) < 0 }
+func (a kvsByKey) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// lookup returns a pointer to the attribute called name or nil.
+func (kvs *Attrs) lookup(name string) *KV {
+	for i := range *kvs {
+		if (*kvs)[i].KeyUnsafe() == name {
+			return &(*kvs)[i]
 		}
 	}
 	return nil
 }
 
+// GetInt returns the value of the attribute name as an integer.
+//
+// If the attribute doesn't exist or can't be parsed def is returned.
+func (kvs *Attrs) GetInt(name string, def int64) int64 {
+	if kv := kvs.lookup(name); kv != nil {
+		if n, err := kv.Int(); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// GetUint returns the value of the attribute name as an unsigned integer.
+//
+// If the attribute doesn't exist or can't be parsed def is returned.
+func (kvs *Attrs) GetUint(name string, def uint64) uint64 {
+	if kv := kvs.lookup(name); kv != nil {
+		if n, err := kv.Uint(); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// GetFloat returns the value of the attribute name as a float.
+//
+// If the attribute doesn't exist or can't be parsed def is returned.
+func (kvs *Attrs) GetFloat(name string, def float64) float64 {
+	if kv := kvs.lookup(name); kv != nil {
+		if n, err := kv.Float(); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// GetBool returns the value of the attribute name as a boolean.
+//
+// If the attribute doesn't exist or can't be parsed def is returned.
+func (kvs *Attrs) GetBool(name string, def bool) bool {
+	if kv := kvs.lookup(name); kv != nil {
+		if b, err := kv.Bool(); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// GetDuration returns the value of the attribute name as a time.Duration.
+//
+// If the attribute doesn't exist or can't be parsed def is returned.
+func (kvs *Attrs) GetDuration(name string, def time.Duration) time.Duration {
+	if kv := kvs.lookup(name); kv != nil {
+		if d, err := kv.Duration(); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// GetTime returns the value of the attribute name as a time.Time using layout.
+//
+// If the attribute doesn't exist or can't be parsed def is returned.
+func (kvs *Attrs) GetTime(name, layout string, def time.Time) time.Time {
+	if kv := kvs.lookup(name); kv != nil {
+		if t, err := kv.Time(layout); err == nil {
+			return t
+		}
+	}
+	return def
+}
+
+// AppendXML appends the attributes to dst as they are written
+// in a start element, each one preceded by a space.
+func (kvs *Attrs) AppendXML(dst []byte) []byte {
+	for i := range *kvs {
+		kv := &(*kvs)[i]
+		dst = append(append(dst, ' '), kv.k...)
+		dst = append(appendQuoted(append(dst, '=', '"'), kv.v), '"')
+	}
+	return dst
+}
+
 // Range passes every attr to fn.
 func (kvs *Attrs) Range(fn func(kv *KV)) {
-	for _, kv := range *kvs {
-		fn(&kv)
+	for i := range *kvs {
+		fn(&(*kvs)[i])
 	}
 }
 
@@ -95,8 +306,8 @@ func (kvs *Attrs) Range(fn func(kv *KV)) {
 //
 // If fn returns false the range loop will break.
 func (kvs *Attrs) RangePre(fn func(kv *KV) bool) {
-	for _, kv := range *kvs {
-		if !fn(&kv) {
+	for i := range *kvs {
+		if !fn(&(*kvs)[i]) {
 			break
 		}
 	}
@@ -104,8 +315,8 @@ func (kvs *Attrs) RangePre(fn func(kv *KV) bool) {
 
 // RangeWithIndex passes every attr and the index to fn.
 func (kvs *Attrs) RangeWithIndex(fn func(i int, kv *KV)) {
-	for i, kv := range *kvs {
-		fn(i, &kv)
+	for i := range *kvs {
+		fn(i, &(*kvs)[i])
 	}
 }
 
@@ -113,7 +324,12 @@ func (kvs *Attrs) RangeWithIndex(fn func(i int, kv *KV)) {
 type StartElement struct {
 	name   []byte
 	attrs  Attrs
+	index  attrIndex
 	hasEnd bool
+	raw    []byte
+
+	id    Name   // handle of the interned name (see Reader.SetNames).
+	iname string // interned name.
 }
 
 // NewStart creats a new StartElement.
@@ -130,16 +346,22 @@ func NewStart(name string, hasEnd bool, attrs *Attrs) *StartElement {
 }
 
 func (s *StartElement) String() string {
-	str := fmt.Sprintf("<%s", s.name)
-	s.attrs.Range(func(kv *KV) {
-		str += fmt.Sprintf(` %s="%s"`, kv.Key(), kv.Value())
-	})
+	return string(s.AppendXML(nil))
+}
+
+// AppendXML appends the XML representation of the element to dst.
+func (s *StartElement) AppendXML(dst []byte) []byte {
+	dst = append(append(dst, '<'), s.name...)
+	dst = s.attrs.AppendXML(dst)
 	if s.hasEnd {
-		str += "/>"
-	} else {
-		str += ">"
+		return append(dst, '/', '>')
 	}
-	return str
+	return append(dst, '>')
+}
+
+// WriteTo writes the XML representation of the element to w.
+func (s *StartElement) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s)
 }
 
 // HasEnd indicates if the StartElement ends as />
@@ -149,10 +371,24 @@ func (s *StartElement) HasEnd() bool {
 }
 
 // Name returns the name of the element.
+//
+// The name is not allocated if it has been interned by the Reader (see Reader.SetNames).
 func (s *StartElement) Name() string {
+	if s.iname == b2s(s.name) {
+		return s.iname
+	}
 	return string(s.name)
 }
 
+// NameID returns the handle of the name interned by the Reader,
+// or zero if the name is not interned (see Reader.SetNames).
+func (s *StartElement) NameID() Name {
+	if s.id != 0 && s.iname == b2s(s.name) {
+		return s.id
+	}
+	return 0
+}
+
 // SetName sets a string as StartElement's name.
 func (s *StartElement) SetName(name string) {
 	s.name = []byte(name)
@@ -176,55 +412,87 @@ func (s *StartElement) NameUnsafe() string {
 }
 
 // Attrs returns the attributes of an element.
+//
+// As the returned list can be modified, calling Attrs invalidates
+// the lookup index used by Attr and AttrBytes.
 func (s *StartElement) Attrs() *Attrs {
+	s.index.reset()
 	return &s.attrs
 }
 
+// Attr returns the attribute called name or nil if it doesn't exist.
+//
+// For elements with many attributes Attr builds a small hash index
+// on the first call, making the subsequent lookups constant time.
+func (s *StartElement) Attr(name string) *KV {
+	if len(s.attrs) < minIndexedAttrs {
+		return s.attrs.lookup(name)
+	}
+	return s.index.get(s.attrs, name)
+}
+
+// AttrBytes returns the attribute called name or nil if it doesn't exist.
+//
+// See Attr.
+func (s *StartElement) AttrBytes(name []byte) *KV {
+	return s.Attr(b2s(name))
+}
+
 // Reset sets the default values to the StartElement.
 func (s *StartElement) Reset() {
 	s.name = s.name[:0]
 	s.attrs = s.attrs[:0]
+	s.index.reset()
 	s.hasEnd = false
+	s.raw = s.raw[:0]
+	s.id, s.iname = 0, ""
+}
+
+// Raw returns the bytes the element has been read from
+// including any whitespace or comment preceding it.
+//
+// Raw is only filled when the Reader keeps the raw bytes (see Reader.SetKeepRaw).
+func (s *StartElement) Raw() []byte {
+	return s.raw
+}
+
+// Kind returns StartKind.
+func (s *StartElement) Kind() Kind {
+	return StartKind
 }
 
-func (s *StartElement) parse(r *bufio.Reader) error {
+func (s *StartElement) parse(r *scanner) error {
 	s.Reset()
 
-	c, err := skipWS(r) // skip any whitespaces
+	_, err := r.skipWS() // skip any whitespaces
 	if err != nil {
 		return err
 	}
-	s.name = append(s.name, c)
+	r.UnreadByte()
 
-	for {
+	s.name, err = r.readName(s.name)
+	for err == nil {
+		var c byte
 		c, err = r.ReadByte()
-		if err != nil || c == ' ' || c == '>' {
-			break
-		}
-
-		switch c {
-		case '/':
+		switch {
+		case err != nil, c == '>':
+			return err
+		case c <= ' ': // doesn't reach the end
+			return s.parseAttrs(r)
+		case c == '/':
 			s.hasEnd = true
-		default:
-			if s.hasEnd { // malformed ??
-				continue
-			}
-			s.name = append(s.name, c)
 		}
-	}
-	if c == ' ' && err == nil { // doesn't reach the end
-		s.attrs = s.attrs[:0]
-		err = s.parseAttrs(r)
+		// anything else after the name is malformed
 	}
 
 	return err
 }
 
-func (s *StartElement) parseAttrs(r *bufio.Reader) (err error) {
+func (s *StartElement) parseAttrs(r *scanner) (err error) {
 	var c byte
 	idx := 0
 	for {
-		c, err = skipWS(r) // skip whitespaces until reaching the key
+		c, err = r.skipWS() // skip whitespaces until reaching the key
 		if err != nil || c == '>' {
 			break
 		}
@@ -235,8 +503,14 @@ func (s *StartElement) parseAttrs(r *bufio.Reader) (err error) {
 		r.UnreadByte()
 
 		// read key
-		err = s.getNextElement(idx).parse(r)
-		if err == nil {
+		kv := s.getNextElement(idx)
+		if r.html {
+			var ok bool
+			if ok, err = kv.parseHTML(r); ok {
+				idx++
+			}
+			s.attrs = s.attrs[:idx]
+		} else if err = kv.parse(r); err == nil {
 			idx++
 		}
 	}
@@ -1,6 +1,7 @@
 package xml
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -69,3 +70,109 @@ func TestStartElementWithAttrsWithEnd(t *testing.T) {
 
 	checkXML(t, xmlStr, "element", attMap, true)
 }
+
+func TestAttrsGetAliases(t *testing.T) {
+	attrs := NewAttrs("a", "1", "b", "2")
+	attrs.Get("b").v = append(attrs.Get("b").v[:0], "3"...)
+	if v := attrs.Get("b").Value(); v != "3" {
+		t.Fatalf("Get should point into the attrs. Got %s", v)
+	}
+}
+
+func TestAttrsEdit(t *testing.T) {
+	attrs := NewAttrs("c", "1", "a", "2", "b", "3")
+
+	attrs.Set("a", "x")
+	attrs.Set("d", "4")
+	if !attrs.Delete("c") {
+		t.Fatal("c not deleted")
+	}
+	if attrs.Delete("c") {
+		t.Fatal("c deleted twice")
+	}
+	attrs.Add("e", "5")
+	attrs.Sort()
+
+	s := NewStart("el", true, attrs)
+	if str, expected := s.String(), `<el a="x" b="3" d="4" e="5"/>`; str != expected {
+		t.Fatalf("Unexpected element: got %s. Expected %s", str, expected)
+	}
+}
+
+func TestStartElementAttrIndex(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<el")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&sb, ` a%d="%d"`, i, i)
+	}
+	sb.WriteString("/>")
+
+	r := NewReader(strings.NewReader(sb.String()))
+	if !r.Next() {
+		t.Fatal(r.Error())
+	}
+	s := r.Element().(*StartElement)
+	for i := 0; i < 40; i++ {
+		kv := s.Attr(fmt.Sprintf("a%d", i))
+		if kv == nil || kv.Value() != fmt.Sprint(i) {
+			t.Fatalf("Unexpected attr a%d: %v", i, kv)
+		}
+	}
+	if s.Attr("missing") != nil {
+		t.Fatal("Unexpected missing attr")
+	}
+
+	s.Attrs().Delete("a0")
+	s.Attrs().Add("z", "last")
+	if s.Attr("a0") != nil || s.Attr("z") == nil {
+		t.Fatal("Index not invalidated after editing the attrs")
+	}
+
+	// editing a retained list doesn't invalidate the index
+	attrs := s.Attrs()
+	s.Attr("a1")
+	attrs.Sort()
+	for i := 1; i < 40; i++ {
+		if kv := s.Attr(fmt.Sprintf("a%d", i)); kv == nil || kv.Value() != fmt.Sprint(i) {
+			t.Fatalf("Unexpected attr a%d after sorting: %v", i, kv)
+		}
+	}
+	attrs.Delete("a1")
+	attrs.Add("y", "new")
+	if s.Attr("a1") != nil || s.Attr("y") == nil || s.Attr("z") == nil {
+		t.Fatal("Stale index after deleting and adding")
+	}
+
+	// same length, other keys
+	var other Attrs
+	for i := 0; i < attrs.Len(); i++ {
+		other.Add(fmt.Sprintf("b%d", i), fmt.Sprint(i))
+	}
+	other.CopyTo(attrs)
+	if s.Attr("a2") != nil || s.Attr("b2") == nil {
+		t.Fatal("Stale index after copying the attrs")
+	}
+}
+
+func TestStartElementQuotesRoundTrip(t *testing.T) {
+	for _, html := range []bool{false, true} {
+		r := NewReader(strings.NewReader(`<a x='say "hi"'/>`))
+		r.SetHTML(html)
+		if !r.Next() {
+			t.Fatal(r.Error())
+		}
+
+		str := r.Element().String()
+		if expected := `<a x="say &quot;hi&quot;"/>`; str != expected {
+			t.Fatalf("html %v: got %s. Expected %s", html, str, expected)
+		}
+
+		r = NewReader(strings.NewReader(str))
+		if !r.Next() {
+			t.Fatal(r.Error())
+		}
+		if kv := r.Start().Attr("x"); kv == nil || kv.Value() != "say &quot;hi&quot;" {
+			t.Fatalf("unexpected attribute %v", kv)
+		}
+	}
+}
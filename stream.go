@@ -0,0 +1,170 @@
+package xml
+
+import "io"
+
+// StreamReader reads documents whose root never closes, like the
+// `<stream:stream>` of XMPP, yielding every child of the root
+// (a stanza) as soon as its end tag has been read.
+type StreamReader struct {
+	r      *Reader
+	root   *StartElement
+	depth  int
+	stanza []Element
+	raw    []byte
+}
+
+// NewStreamReader creates a StreamReader reading from r.
+//
+// r records the bytes it reads from then on to return the raw stanzas.
+func NewStreamReader(r *Reader) *StreamReader {
+	r.r.record = true
+	return &StreamReader{
+		r: r,
+	}
+}
+
+// Root returns the root element, or nil if it hasn't been read yet.
+func (s *StreamReader) Root() *StartElement {
+	return s.root
+}
+
+// Next reads until the next stanza is complete.
+//
+// Next returns false when the root is closed, the input ends or
+// the reader fails. The text found directly inside the root, like
+// the whitespaces used as keepalives, is ignored.
+func (s *StreamReader) Next() bool {
+	s.stanza = s.stanza[:0]
+	s.raw = s.raw[:0]
+
+	r := s.r
+	for r.Next() {
+		e := r.Element()
+		if s.root == nil {
+			if start, ok := e.(*StartElement); ok {
+				s.root = detach(start).(*StartElement)
+				if start.HasEnd() {
+					r.err = io.EOF
+					break
+				}
+			}
+			continue
+		}
+
+		switch e := e.(type) {
+		case *StartElement:
+			if !e.HasEnd() {
+				s.depth++
+			}
+		case *EndElement:
+			if s.depth == 0 { // root closed
+				r.err = io.EOF
+				return false
+			}
+			s.depth--
+		case *TextElement:
+			if s.depth == 0 {
+				continue
+			}
+		}
+
+		if len(s.stanza) == 0 {
+			s.raw = append(s.raw, r.r.raw[r.tok:]...)
+		} else {
+			s.raw = append(s.raw, r.r.raw...)
+		}
+		s.stanza = append(s.stanza, detach(e))
+
+		if s.depth == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stanza returns the elements of the last stanza read, from its
+// StartElement to its EndElement.
+//
+// The elements don't belong to the Reader, so they can be kept.
+// The slice is reused by the next call to Next.
+func (s *StreamReader) Stanza() []Element {
+	return s.stanza
+}
+
+// Raw returns the bytes of the last stanza read as they were in the input.
+//
+// The slice is reused by the next call to Next.
+func (s *StreamReader) Raw() []byte {
+	return s.raw
+}
+
+// Error returns the error that stopped the reader.
+//
+// It is io.EOF if the root element has been closed.
+func (s *StreamReader) Error() error {
+	return s.r.Error()
+}
+
+// detach returns a copy of e not belonging to any Reader.
+func detach(e Element) Element {
+	switch e := e.(type) {
+	case *StartElement:
+		c := &StartElement{
+			name:   append([]byte(nil), e.name...),
+			hasEnd: e.hasEnd,
+		}
+		e.attrs.CopyTo(&c.attrs)
+		return c
+	case *EndElement:
+		return NewEnd(e.Name())
+	case *TextElement:
+		return NewText(e.String())
+	}
+	return e
+}
+
+// OpenStream writes root without closing it, starting a stream
+// of stanzas written with WriteStanza.
+func (w *Writer) OpenStream(root *StartElement) error {
+	w.stream = append(w.stream[:0], root.name...)
+
+	w.out = append(append(w.out[:0], '<'), root.name...)
+	w.out = append(root.attrs.AppendXML(w.out), '>')
+	if err := w.writeBytes(w.out); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// WriteStanza writes the elements of a stanza and flushes
+// the underlying writer if it has a Flush method, like bufio.Writer.
+func (w *Writer) WriteStanza(es ...Element) error {
+	for _, e := range es {
+		if err := w.Write(e); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// CloseStream writes the end of the root opened with OpenStream.
+func (w *Writer) CloseStream() error {
+	w.out = append(append(append(w.out[:0], '<', '/'), w.stream...), '>')
+	if err := w.writeBytes(w.out); err != nil {
+		return err
+	}
+	w.stream = w.stream[:0]
+	return w.flush()
+}
+
+// flush flushes the underlying writer if it supports it.
+func (w *Writer) flush() error {
+	switch f := w.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }: // http.Flusher
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package xml
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamReader(t *testing.T) {
+	pr, pw := io.Pipe()
+	s := NewStreamReader(NewReader(pr))
+
+	stanzas := make(chan string)
+	go func() {
+		defer close(stanzas)
+		for s.Next() {
+			stanzas <- string(s.Raw())
+		}
+	}()
+
+	pw.Write([]byte(`<?xml version='1.0'?><stream:stream to="example.com" xmlns:stream="http://etherx.jabber.org/streams">`))
+	pw.Write([]byte("\n "))
+
+	const msg = `<message to="a@example.com"><body>hi <b>there</b></body></message>`
+	pw.Write([]byte(msg))
+	// the stanza is yielded before the stream goes on
+	if got := <-stanzas; got != msg {
+		t.Fatalf("unexpected stanza %q", got)
+	}
+	if s.Root().Name() != "stream:stream" || s.Root().Attrs().Get("to").Value() != "example.com" {
+		t.Fatalf("unexpected root %s", s.Root())
+	}
+
+	pw.Write([]byte(" <presence/>"))
+	if got := <-stanzas; got != "<presence/>" {
+		t.Fatalf("unexpected stanza %q", got)
+	}
+
+	pw.Write([]byte("</stream:stream>"))
+	if _, ok := <-stanzas; ok {
+		t.Fatal("stanza after closing the stream")
+	}
+	if s.Error() != io.EOF {
+		t.Fatalf("expected EOF. Got %v", s.Error())
+	}
+}
+
+func TestStreamReaderStanza(t *testing.T) {
+	s := NewStreamReader(NewReader(strings.NewReader(`<root><a x="1">text<b/></a>`)))
+	if !s.Next() {
+		t.Fatal(s.Error())
+	}
+
+	stanza := s.Stanza()
+	// the elements are detached from the reader
+	s.Next()
+
+	var b strings.Builder
+	for _, e := range stanza {
+		b.WriteString(e.String())
+	}
+	if b.String() != `<a x="1">text<b/></a>` {
+		t.Fatalf("unexpected stanza %q", b.String())
+	}
+}
+
+func TestWriterStream(t *testing.T) {
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	w := NewWriter(bw)
+
+	if err := w.OpenStream(NewStart("stream:stream", false, NewAttrs("to", "example.com"))); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `<stream:stream to="example.com">` {
+		t.Fatalf("the root hasn't been flushed: %q", out.String())
+	}
+
+	err := w.WriteStanza(NewStart("message", false, nil), NewText("hi"), NewEnd("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "<message>hi</message>") {
+		t.Fatalf("the stanza hasn't been flushed: %q", out.String())
+	}
+
+	if err := w.CloseStream(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "</stream:stream>") {
+		t.Fatalf("the stream hasn't been closed: %q", out.String())
+	}
+}
@@ -0,0 +1,157 @@
+package xml
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+// ErrNotStart is returned when reading the content of an element
+// and the last element read is not a StartElement.
+var ErrNotStart = errors.New("xml: the current element is not a StartElement")
+
+// ReadText reads the content of the current StartElement returning
+// the concatenation of the text of all its descendants.
+//
+// The element is consumed until its EndElement, which becomes
+// the current element, so the reading loop continues after it.
+// The text is handled as set by SetWhitespace.
+func (r *Reader) ReadText() (string, error) {
+	b, err := r.readText()
+	return string(b), err
+}
+
+// ReadInt reads the text of the current StartElement as a base 10 integer.
+//
+// See ReadText.
+func (r *Reader) ReadInt() (int64, error) {
+	b, err := r.readText()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(b2s(trimWS(b)), 10, 64)
+}
+
+// ReadFloat reads the text of the current StartElement as a floating point number.
+//
+// See ReadText.
+func (r *Reader) ReadFloat() (float64, error) {
+	b, err := r.readText()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(b2s(trimWS(b)), 64)
+}
+
+// ReadBool reads the text of the current StartElement as a boolean.
+//
+// See ReadText and TextElement.Bool.
+func (r *Reader) ReadBool() (bool, error) {
+	b, err := r.readText()
+	if err != nil {
+		return false, err
+	}
+	return parseBool(b2s(trimWS(b)))
+}
+
+// ReadInnerXML reads the content of the current StartElement
+// returning its markup as found in the input.
+//
+// The element is consumed until its EndElement like in ReadText.
+func (r *Reader) ReadInnerXML() (string, error) {
+	if r.Start() == nil {
+		return "", ErrNotStart
+	}
+	r.sub = r.sub[:0]
+	err := r.readMarkup()
+	return string(r.sub), err
+}
+
+// ReadOuterXML reads the current StartElement returning its markup
+// including the element itself.
+//
+// The start tag is serialized from the element (see StartElement.AppendXML)
+// and the content is returned as found in the input.
+// The element is consumed until its EndElement like in ReadText.
+func (r *Reader) ReadOuterXML() (string, error) {
+	s := r.Start()
+	if s == nil {
+		return "", ErrNotStart
+	}
+	n := len(s.name)
+	r.sub = s.AppendXML(r.sub[:0])
+	if s.hasEnd {
+		return string(r.sub), nil
+	}
+
+	err := r.readMarkup()
+	if err == nil {
+		r.sub = append(r.sub, '<', '/')
+		r.sub = append(r.sub, r.sub[1:n+1]...)
+		r.sub = append(r.sub, '>')
+	}
+	return string(r.sub), err
+}
+
+func (r *Reader) readText() ([]byte, error) {
+	r.sub = r.sub[:0]
+	err := r.readSubtree(func(end bool) {
+		if t := r.Text(); t != nil {
+			r.sub = append(r.sub, *t...)
+		}
+	})
+	return r.sub, err
+}
+
+// readMarkup appends to r.sub the input consumed until
+// the end of the current StartElement.
+func (r *Reader) readMarkup() error {
+	record := r.r.record
+	r.r.record = true
+
+	err := r.readSubtree(func(end bool) {
+		if end { // without the end tag
+			r.sub = append(r.sub, r.r.raw[:r.tok]...)
+		} else {
+			r.sub = append(r.sub, r.r.raw...)
+		}
+	})
+
+	r.r.record = record
+	if !record {
+		r.r.raw = r.r.raw[:0]
+	}
+	return err
+}
+
+// readSubtree reads until the EndElement of the current StartElement
+// calling fn after reading every element, including the EndElement.
+func (r *Reader) readSubtree(fn func(end bool)) error {
+	s := r.Start()
+	if s == nil {
+		return ErrNotStart
+	}
+	if s.hasEnd {
+		return nil
+	}
+
+	for depth := 1; r.Next(); {
+		switch r.Kind() {
+		case StartKind:
+			if !r.Start().hasEnd {
+				depth++
+			}
+		case EndKind:
+			if depth--; depth == 0 {
+				fn(true)
+				return nil
+			}
+		}
+		fn(false)
+	}
+
+	if r.err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return r.err
+}
@@ -0,0 +1,97 @@
+package xml
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderReadText(t *testing.T) {
+	const str = `<doc><title>Hello <b>world</b>!</title><n> 42 </n><empty/><after/></doc>`
+
+	r := NewReader(strings.NewReader(str))
+	var got []string
+	for r.Next() {
+		s := r.Start()
+		if s == nil {
+			continue
+		}
+		switch s.NameUnsafe() {
+		case "title", "empty":
+			text, err := r.ReadText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, text)
+		case "n":
+			n, err := r.ReadInt()
+			if err != nil || n != 42 {
+				t.Fatalf("unexpected int %d: %v", n, err)
+			}
+			if end := r.End(); end == nil || end.NameUnsafe() != "n" {
+				t.Fatalf("expected the end of n. Got %s", r.Element())
+			}
+		case "after":
+			got = append(got, "after")
+		}
+	}
+
+	if s := strings.Join(got, "|"); s != "Hello world!||after" {
+		t.Fatalf("unexpected texts %q", got)
+	}
+}
+
+func TestReaderReadXML(t *testing.T) {
+	const str = `<doc><p id="1">Hello <b>big</b> <!-- c --><i/> world</p><q/></doc>`
+
+	for _, keepRaw := range []bool{false, true} {
+		r := NewReader(strings.NewReader(str))
+		r.SetKeepRaw(keepRaw)
+
+		var inner, outer string
+		var err error
+		for r.Next() {
+			if s := r.Start(); s != nil && s.NameUnsafe() == "p" {
+				if keepRaw {
+					outer, err = r.ReadOuterXML()
+				} else {
+					inner, err = r.ReadInnerXML()
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Next()
+				q := r.Start()
+				if q == nil || q.NameUnsafe() != "q" {
+					t.Fatalf("expected q after p. Got %s", r.Element())
+				}
+				if keepRaw && string(q.Raw()) != "<q/>" {
+					t.Fatalf("unexpected raw %q", q.Raw())
+				}
+			}
+		}
+
+		if keepRaw {
+			if expected := `<p id="1">Hello <b>big</b> <!-- c --><i/> world</p>`; outer != expected {
+				t.Fatalf("got %s. Expected %s", outer, expected)
+			}
+		} else if expected := `Hello <b>big</b> <!-- c --><i/> world`; inner != expected {
+			t.Fatalf("got %s. Expected %s", inner, expected)
+		}
+		if !keepRaw && len(r.Raw()) > 0 {
+			t.Fatalf("unexpected raw bytes %q", r.Raw())
+		}
+	}
+}
+
+func TestReaderReadTextErrors(t *testing.T) {
+	r := NewReader(strings.NewReader(`<a>text`))
+	if _, err := r.ReadText(); err != ErrNotStart {
+		t.Fatalf("expected ErrNotStart. Got %v", err)
+	}
+
+	r.Next()
+	if _, err := r.ReadText(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF. Got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package xml
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// The SWAR (SIMD within a register) functions below test 8 bytes at once
+// loading them into an uint64. Every mask has the high bit of a byte set
+// when the byte matches, so the index of the first match is the number
+// of trailing zeros divided by 8.
+
+const (
+	lsb = 0x0101010101010101
+	msb = 0x8080808080808080
+)
+
+// gtMask returns the high bits of the bytes of x greater than n (n < 128).
+func gtMask(x uint64, n byte) uint64 {
+	// masking the high bits first prevents carries between bytes.
+	return ((x &^ msb) + lsb*uint64(127-n) | x) & msb
+}
+
+// eqMask returns the high bits of the bytes of x equal to c.
+func eqMask(x uint64, c byte) uint64 {
+	y := x ^ (lsb * uint64(c))
+	return ^((y &^ msb) + lsb*0x7f | y) & msb
+}
+
+// indexNonSpaceSWAR returns the index of the first byte greater than ' '
+// or len(b) if all the bytes are whitespaces or control characters.
+func indexNonSpaceSWAR(b []byte) int {
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		if m := gtMask(binary.LittleEndian.Uint64(b[i:]), ' '); m != 0 {
+			return i + bits.TrailingZeros64(m)/8
+		}
+	}
+	for ; i < len(b) && b[i] <= ' '; i++ {
+	}
+	return i
+}
+
+// indexNameEnd returns the index of the first byte that can't be part
+// of a name: a whitespace, '>', '/' or '=', or len(b) if there's none.
+func indexNameEnd(b []byte) int {
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		x := binary.LittleEndian.Uint64(b[i:])
+		m := ^gtMask(x, ' ')&msb | eqMask(x, '>') | eqMask(x, '/') | eqMask(x, '=')
+		if m != 0 {
+			return i + bits.TrailingZeros64(m)/8
+		}
+	}
+	for ; i < len(b) && !isNameDelim(b[i]); i++ {
+	}
+	return i
+}
+
+func isNameDelim(c byte) bool {
+	return c <= ' ' || c == '>' || c == '/' || c == '='
+}
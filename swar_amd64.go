@@ -0,0 +1,11 @@
+//go:build amd64 && !purego
+
+package xml
+
+// indexNonSpace returns the index of the first byte greater than ' '
+// or len(b) if all the bytes are whitespaces or control characters.
+//
+// It tests 16 bytes at once using SSE2.
+//
+//go:noescape
+func indexNonSpace(b []byte) int
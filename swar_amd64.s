@@ -0,0 +1,45 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func indexNonSpace(b []byte) int
+TEXT ·indexNonSpace(SB), NOSPLIT, $0-32
+	MOVQ b_base+0(FP), SI
+	MOVQ b_len+8(FP), BX
+	XORQ AX, AX
+
+	// X1 holds 16 spaces
+	MOVQ $0x2020202020202020, DX
+	MOVQ DX, X1
+	PUNPCKLQDQ X1, X1
+
+loop16:
+	LEAQ 16(AX), CX
+	CMPQ CX, BX
+	JA   tail
+	MOVOU (SI)(AX*1), X0
+	// the bytes lower or equal than ' ' are equal to max(b, ' ')
+	PMAXUB  X1, X0
+	PCMPEQB X1, X0
+	PMOVMSKB X0, DX
+	XORL $0xffff, DX
+	JNZ  found
+	MOVQ CX, AX
+	JMP  loop16
+
+tail:
+	CMPQ AX, BX
+	JAE  done
+	MOVBLZX (SI)(AX*1), DX
+	CMPL DX, $0x20
+	JHI  done
+	INCQ AX
+	JMP  tail
+
+found:
+	BSFL DX, DX
+	ADDQ DX, AX
+
+done:
+	MOVQ AX, ret+24(FP)
+	RET
@@ -0,0 +1,9 @@
+//go:build !amd64 || purego
+
+package xml
+
+// indexNonSpace returns the index of the first byte greater than ' '
+// or len(b) if all the bytes are whitespaces or control characters.
+func indexNonSpace(b []byte) int {
+	return indexNonSpaceSWAR(b)
+}
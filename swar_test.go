@@ -0,0 +1,144 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func indexNonSpaceNaive(b []byte) int {
+	for i, c := range b {
+		if c > ' ' {
+			return i
+		}
+	}
+	return len(b)
+}
+
+func indexNameEndNaive(b []byte) int {
+	for i, c := range b {
+		if isNameDelim(c) {
+			return i
+		}
+	}
+	return len(b)
+}
+
+func TestSWAR(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	alphabet := []byte{0, '\t', '\n', ' ', '!', '/', '=', '>', 'a', 0x7f, 0x80, 0xa0, 0xff}
+
+	for n := 0; n < 100; n++ {
+		b := make([]byte, n)
+		for i := 0; i < 200; i++ {
+			for j := range b {
+				// mostly the bytes being skipped
+				if rnd.Intn(8) == 0 {
+					b[j] = alphabet[rnd.Intn(len(alphabet))]
+				} else if i&1 == 0 {
+					b[j] = ' '
+				} else {
+					b[j] = 'a'
+				}
+			}
+
+			if got, expected := indexNonSpace(b), indexNonSpaceNaive(b); got != expected {
+				t.Fatalf("indexNonSpace(%q) = %d. Expected %d", b, got, expected)
+			}
+			if got, expected := indexNonSpaceSWAR(b), indexNonSpaceNaive(b); got != expected {
+				t.Fatalf("indexNonSpaceSWAR(%q) = %d. Expected %d", b, got, expected)
+			}
+			if got, expected := indexNameEnd(b), indexNameEndNaive(b); got != expected {
+				t.Fatalf("indexNameEnd(%q) = %d. Expected %d", b, got, expected)
+			}
+		}
+	}
+}
+
+func readAll(r *Reader) string {
+	var b strings.Builder
+	for r.Next() {
+		switch e := r.Element().(type) {
+		case *StartElement:
+			b.WriteString("<" + e.Name())
+			e.Attrs().Range(func(kv *KV) {
+				b.WriteString(" " + kv.Key() + "=" + kv.Value())
+			})
+			if e.HasEnd() {
+				b.WriteString("/")
+			}
+			b.WriteString(">")
+		case *EndElement:
+			b.WriteString("</" + e.Name() + ">")
+		case *TextElement:
+			b.WriteString(e.String())
+		}
+	}
+	if err := r.Error(); err != io.EOF {
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func TestScannerBufferBoundaries(t *testing.T) {
+	str := `<?xml version="1.0"?>
+<!DOCTYPE doc [<!ENTITY e "entity">]>
+<doc    xmlns="urn:doc">
+	<!-- comment -->
+	<item    id = "1"   name='first'  >text &e;</item>
+	<empty/><empty  a="b"  />
+	<![CDATA[ skipped ]]>
+	<long>` + strings.Repeat("long text ", 100) + `</long   >
+</doc>`
+
+	expected := readAll(NewReader(strings.NewReader(str)))
+
+	r := NewReader(iotest.OneByteReader(strings.NewReader(str)))
+	if got := readAll(r); got != expected {
+		t.Fatalf("one byte reader:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	for size := 1; size < 64; size++ {
+		r := &Reader{
+			r:           newScanner(iotest.HalfReader(strings.NewReader(str)), size),
+			entityLimit: defaultEntityLimit,
+		}
+		if got := readAll(r); got != expected {
+			t.Fatalf("buffer size %d:\n%s\nexpected:\n%s", size, got, expected)
+		}
+	}
+}
+
+func benchmarkIndex(b *testing.B, fn func([]byte) int, data []byte) {
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		fn(data)
+	}
+}
+
+var spaces = append(bytes.Repeat([]byte(" \t\r\n"), 256), 'a')
+
+func BenchmarkIndexNonSpaceNaive(b *testing.B) {
+	benchmarkIndex(b, indexNonSpaceNaive, spaces)
+}
+
+func BenchmarkIndexNonSpaceSWAR(b *testing.B) {
+	benchmarkIndex(b, indexNonSpaceSWAR, spaces)
+}
+
+func BenchmarkIndexNonSpace(b *testing.B) {
+	benchmarkIndex(b, indexNonSpace, spaces)
+}
+
+var name = append(bytes.Repeat([]byte("abcdefgh"), 128), '>')
+
+func BenchmarkIndexNameEndNaive(b *testing.B) {
+	benchmarkIndex(b, indexNameEndNaive, name)
+}
+
+func BenchmarkIndexNameEnd(b *testing.B) {
+	benchmarkIndex(b, indexNameEnd, name)
+}
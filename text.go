@@ -1,9 +1,31 @@
 package xml
 
-import "bufio"
+import (
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var textPool = sync.Pool{
+	New: func() interface{} {
+		return new(TextElement)
+	},
+}
+
+// releaseText returns the TextElement to the pool.
+func releaseText(t *TextElement) {
+	textPool.Put(t)
+}
 
 // TextElement represents a XML text.
-type TextElement string
+//
+// The TextElements returned by a Reader are pooled: they are only valid
+// until the next call to Next, which reuses them. Use String to keep the text.
+//
+// TextElement used to be a string. Conversions like string(*t) or TextElement("x")
+// keep working, but comparisons with strings must use String or Unsafe.
+type TextElement []byte
 
 // NewText creates a new TextElement.
 func NewText(str string) *TextElement {
@@ -11,11 +33,88 @@ func NewText(str string) *TextElement {
 	return &t
 }
 
-func (t *TextElement) parse(_ *bufio.Reader) error {
-	return nil
+// Kind returns TextKind.
+func (t *TextElement) Kind() Kind {
+	return TextKind
 }
 
 // String returns the string representation of TextElement.
 func (t *TextElement) String() string {
 	return string(*t)
 }
+
+// AppendXML appends the text to dst.
+func (t *TextElement) AppendXML(dst []byte) []byte {
+	return append(dst, *t...)
+}
+
+// WriteTo writes the text to w.
+func (t *TextElement) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(*t)
+	return int64(n), err
+}
+
+// Bytes returns the text.
+func (t *TextElement) Bytes() []byte {
+	return *t
+}
+
+// Unsafe returns a string holding the text.
+//
+// This function differs from String() on using unsafe methods.
+func (t *TextElement) Unsafe() string {
+	return b2s(*t)
+}
+
+// SetText sets the text.
+func (t *TextElement) SetText(str string) {
+	*t = append((*t)[:0], str...)
+}
+
+// SetTextBytes sets the text in bytes.
+func (t *TextElement) SetTextBytes(text []byte) {
+	*t = append((*t)[:0], text...)
+}
+
+// Reset sets the default values to the TextElement.
+func (t *TextElement) Reset() {
+	*t = (*t)[:0]
+}
+
+// Raw returns nil: the raw bytes of a text are returned
+// by Reader.Raw right after reading it.
+func (t *TextElement) Raw() []byte {
+	return nil
+}
+
+// Int parses the text as a base 10 integer.
+func (t *TextElement) Int() (int64, error) {
+	return strconv.ParseInt(b2s(trimWS(*t)), 10, 64)
+}
+
+// Uint parses the text as a base 10 unsigned integer.
+func (t *TextElement) Uint() (uint64, error) {
+	return strconv.ParseUint(b2s(trimWS(*t)), 10, 64)
+}
+
+// Float parses the text as a floating point number.
+func (t *TextElement) Float() (float64, error) {
+	return strconv.ParseFloat(b2s(trimWS(*t)), 64)
+}
+
+// Bool parses the text as a boolean.
+//
+// Valid values are 1, 0, true and false.
+func (t *TextElement) Bool() (bool, error) {
+	return parseBool(b2s(trimWS(*t)))
+}
+
+// Duration parses the text as a time.Duration (see time.ParseDuration).
+func (t *TextElement) Duration() (time.Duration, error) {
+	return time.ParseDuration(b2s(trimWS(*t)))
+}
+
+// Time parses the text as a time.Time formatted with layout.
+func (t *TextElement) Time(layout string) (time.Time, error) {
+	return parseTime(layout, b2s(trimWS(*t)))
+}
@@ -0,0 +1,91 @@
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTextElement(t *testing.T) {
+	r := NewReader(strings.NewReader(`<a> 42 </a>`))
+	r.SetWhitespace(WhitespacePreserve)
+	r.Next()
+	r.Next()
+
+	text := r.Text()
+	if text == nil {
+		t.Fatalf("expected text. Got %s", r.Element())
+	}
+	if string(text.Bytes()) != " 42 " || text.Unsafe() != " 42 " || text.String() != " 42 " {
+		t.Fatalf("unexpected text %q", text.Bytes())
+	}
+	if n, err := text.Int(); err != nil || n != 42 {
+		t.Fatalf("unexpected int %d: %v", n, err)
+	}
+}
+
+func TestReaderAssignNextTyped(t *testing.T) {
+	const str = `<row><name>cell</name><n> 12 </n><v>1.5</v></row>`
+
+	var (
+		name []byte
+		n    int64
+		v    float64
+	)
+	name = make([]byte, 0, 16)
+	buf := name[:1]
+
+	r := NewReader(strings.NewReader(str))
+	for r.Next() {
+		if s := r.Start(); s != nil {
+			switch s.NameUnsafe() {
+			case "name":
+				r.AssignNextBytes(&name)
+			case "n":
+				r.AssignNextInt(&n)
+			case "v":
+				r.AssignNextFloat(&v)
+			}
+		}
+		if r.Kind() == TextKind {
+			t.Fatalf("unexpected text %s", r.Element())
+		}
+	}
+
+	if string(name) != "cell" || n != 12 || v != 1.5 {
+		t.Fatalf("unexpected values %q %d %v", name, n, v)
+	}
+	if &buf[0] != &name[0] {
+		t.Fatal("the slice has not been reused")
+	}
+}
+
+func TestReaderAssignNextIntError(t *testing.T) {
+	var n int64
+	r := NewReader(strings.NewReader(`<n>x</n>`))
+	for r.Next() {
+		r.AssignNextInt(&n)
+	}
+	if r.Error() == nil || !strings.Contains(r.Error().Error(), "invalid syntax") {
+		t.Fatalf("expected a syntax error. Got %v", r.Error())
+	}
+}
+
+func TestReaderTextAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items with the race detector")
+	}
+	data := []byte(strings.Repeat(`<c>text</c>`, 100))
+	rd := bytes.NewReader(data)
+	r := NewReader(rd)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		rd.Reset(data)
+		r.Reset(rd)
+		for r.Next() {
+		}
+	})
+	if allocs > 0 {
+		t.Fatalf("expected no allocations. Got %v", allocs)
+	}
+}
@@ -0,0 +1,217 @@
+package xml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+const (
+	xmlnsPrefix = "xmlns"
+	xmlPrefix   = "xml"
+	xmlURL      = "http://www.w3.org/XML/1998/namespace"
+)
+
+// tokenReader exposes the elements of a Reader as encoding/xml tokens.
+type tokenReader struct {
+	r   *Reader
+	h   HandlerFuncs
+	buf []byte
+	// toks are the tokens read but not returned yet, like the CDATA sections
+	// preceding the last element or the end of a self-closed element.
+	toks []xml.Token
+}
+
+// TokenReader returns an encoding/xml TokenReader reading from r.
+//
+// The returned TokenReader can be used with xml.NewTokenDecoder
+// to decode values using the reader's scanner. As with xml.Decoder.RawToken
+// the names of the tokens keep their prefix in Name.Space,
+// leaving the namespace translation to xml.Decoder.
+//
+// CDATA sections are returned as xml.CharData. Comments, processing instructions
+// and directives are not returned as the Reader doesn't report them.
+func (r *Reader) TokenReader() xml.TokenReader {
+	t := &tokenReader{r: r}
+	t.h.OnCDATA = t.cdata
+	return t
+}
+
+func (t *tokenReader) Token() (xml.Token, error) {
+	for len(t.toks) == 0 {
+		if !t.read() && len(t.toks) == 0 {
+			err := t.r.Error()
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+	}
+
+	tok := t.toks[0]
+	t.toks = append(t.toks[:0], t.toks[1:]...)
+	return tok, nil
+}
+
+// read reads the next element queueing its tokens.
+func (t *tokenReader) read() bool {
+	h := t.r.handler
+	t.r.handler = &t.h
+	ok := t.r.Next()
+	t.r.handler = h
+	if !ok {
+		return false
+	}
+
+	switch e := t.r.Element().(type) {
+	case *StartElement:
+		se := xml.StartElement{
+			Name: splitName(e.NameUnsafe()),
+			Attr: make([]xml.Attr, 0, e.attrs.Len()),
+		}
+		e.attrs.Range(func(kv *KV) {
+			t.buf = Unescape(t.buf[:0], kv.v)
+			se.Attr = append(se.Attr, xml.Attr{
+				Name:  splitName(kv.KeyUnsafe()),
+				Value: string(t.buf),
+			})
+		})
+		t.toks = append(t.toks, se)
+		if e.HasEnd() {
+			t.toks = append(t.toks, xml.EndElement{Name: se.Name})
+		}
+	case *EndElement:
+		t.toks = append(t.toks, xml.EndElement{Name: splitName(e.NameUnsafe())})
+	case *TextElement:
+		t.toks = append(t.toks, xml.CharData(Unescape(nil, *e)))
+	}
+	return true
+}
+
+// cdata queues a CDATA section found while reading the next element.
+func (t *tokenReader) cdata(data []byte) error {
+	t.toks = append(t.toks, xml.CharData(append([]byte(nil), data...)))
+	return nil
+}
+
+// splitName splits a prefixed name like `p:price` into an xml.Name.
+//
+// The strings of the returned name are copies of name.
+func splitName(name string) xml.Name {
+	if i := strings.IndexByte(name, ':'); i > 0 {
+		return xml.Name{
+			Space: strings.Clone(name[:i]),
+			Local: strings.Clone(name[i+1:]),
+		}
+	}
+	return xml.Name{Local: strings.Clone(name)}
+}
+
+// nsBinding binds a namespace prefix to its URL.
+type nsBinding struct {
+	prefix, url string
+}
+
+// WriteToken writes an encoding/xml token.
+//
+// The names can either be raw, holding the prefix in Name.Space,
+// or translated by xml.Decoder.Token, holding the namespace URL.
+// In the latter case the URL is replaced by the prefix declared
+// with the xmlns attributes written before.
+func (w *Writer) WriteToken(t xml.Token) (err error) {
+	switch t := t.(type) {
+	case xml.StartElement:
+		w.marks = append(w.marks, len(w.ns))
+		for _, attr := range t.Attr {
+			if attr.Name.Space == xmlnsPrefix {
+				w.ns = append(w.ns, nsBinding{attr.Name.Local, attr.Value})
+			} else if attr.Name.Space == "" && attr.Name.Local == xmlnsPrefix {
+				w.ns = append(w.ns, nsBinding{"", attr.Value})
+			}
+		}
+
+		s := &w.start
+		s.Reset()
+		s.name = w.appendName(s.name, t.Name)
+		for _, attr := range t.Attr {
+			kv := s.attrs.next()
+			if attr.Name.Space == xmlnsPrefix {
+				kv.k = append(append(kv.k[:0], "xmlns:"...), attr.Name.Local...)
+			} else {
+				kv.k = w.appendName(kv.k[:0], attr.Name)
+			}
+			kv.v = escapeAttr(kv.v[:0], []byte(attr.Value))
+		}
+		err = w.Write(s)
+	case xml.EndElement:
+		w.buf = append(append(w.buf[:0], "</"...), w.appendName(nil, t.Name)...)
+		w.buf = append(w.buf, '>')
+		if n := len(w.marks); n > 0 {
+			w.ns = w.ns[:w.marks[n-1]]
+			w.marks = w.marks[:n-1]
+		}
+		err = w.writeBytes(w.buf)
+	case xml.CharData:
+		w.buf = escapeText(w.buf[:0], t)
+		err = w.writeBytes(w.buf)
+	case xml.Comment:
+		err = writeString(w.w, "<!--", string(t), "-->")
+	case xml.ProcInst:
+		if len(t.Inst) > 0 {
+			err = writeString(w.w, "<?", t.Target, " ", string(t.Inst), "?>")
+		} else {
+			err = writeString(w.w, "<?", t.Target, "?>")
+		}
+	case xml.Directive:
+		err = writeString(w.w, "<!", string(t), ">")
+	}
+
+	return err
+}
+
+// CopyTokens writes the tokens read from tr until io.EOF is found.
+//
+// tr can be an xml.Decoder, which makes possible to pipe
+// encoding/xml into the Writer.
+func (w *Writer) CopyTokens(tr xml.TokenReader) error {
+	for {
+		t, err := tr.Token()
+		if t != nil {
+			if werr := w.WriteToken(t); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return err
+		}
+	}
+}
+
+// appendName appends name to dst as `prefix:local`.
+func (w *Writer) appendName(dst []byte, name xml.Name) []byte {
+	if prefix := w.prefix(name.Space); prefix != "" {
+		dst = append(append(dst, prefix...), ':')
+	}
+	return append(dst, name.Local...)
+}
+
+// prefix returns the prefix bound to the namespace space.
+//
+// If space isn't bound it is considered to be a prefix itself.
+func (w *Writer) prefix(space string) string {
+	if space == "" || space == xmlnsPrefix {
+		return space
+	}
+	if space == xmlURL {
+		return xmlPrefix
+	}
+	for i := len(w.ns) - 1; i >= 0; i-- {
+		if w.ns[i].url == space {
+			return w.ns[i].prefix
+		}
+	}
+	return space
+}
@@ -0,0 +1,97 @@
+package xml
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type bookstore struct {
+	Books []struct {
+		Category string `xml:"category,attr"`
+		Title    struct {
+			Lang string `xml:"lang,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"title"`
+		Authors []string `xml:"author"`
+		Year    int      `xml:"year"`
+		Price   float64  `xml:"urn:schemas-books-com:prices price"`
+	} `xml:"book"`
+}
+
+func TestTokenReaderDecode(t *testing.T) {
+	var expected, got bookstore
+	if err := xml.Unmarshal([]byte(benchStr), &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	d := xml.NewTokenDecoder(NewReader(strings.NewReader(benchStr)).TokenReader())
+	if err := d.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Books) != 4 || !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected decoding:\n%+v\nExpected:\n%+v", got, expected)
+	}
+}
+
+func TestTokenReaderTokens(t *testing.T) {
+	const str = `<a xmlns:p="urn:p" p:k="1 &lt; 2"><p:b/>x &amp; y<c>&#65;&#x42;</c></a>`
+
+	tokens := func(tr xml.TokenReader) (ts []xml.Token) {
+		d := xml.NewTokenDecoder(tr)
+		for {
+			tok, err := d.Token()
+			if err == io.EOF {
+				return ts
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts = append(ts, xml.CopyToken(tok))
+		}
+	}
+
+	expected := tokens(xml.NewDecoder(strings.NewReader(str)))
+	got := tokens(NewReader(strings.NewReader(str)).TokenReader())
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected tokens:\n%#v\nExpected:\n%#v", got, expected)
+	}
+}
+
+func TestWriterCopyTokens(t *testing.T) {
+	const str = `<?xml version="1.0"?><!-- c --><a xmlns="urn:a" xmlns:p="urn:p" p:k="&quot;v&quot;"><p:b xml:lang="en">x &amp; y</p:b><c/></a>`
+	const expected = `<?xml version="1.0"?><!-- c --><a xmlns="urn:a" xmlns:p="urn:p" p:k="&quot;v&quot;"><p:b xml:lang="en">x &amp; y</p:b><c></c></a>`
+
+	var sb strings.Builder
+	if err := NewWriter(&sb).CopyTokens(xml.NewDecoder(strings.NewReader(str))); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != expected {
+		t.Fatalf("Unexpected output:\n%s\nExpected:\n%s", sb.String(), expected)
+	}
+}
+
+func TestTokenReaderCDATA(t *testing.T) {
+	const str = `<t><v><![CDATA[x<y]]></v><w>a<![CDATA[&b]]>c</w><e/></t>`
+
+	type doc struct {
+		V string `xml:"v"`
+		W string `xml:"w"`
+		E string `xml:"e"`
+	}
+
+	var expected, got doc
+	if err := xml.Unmarshal([]byte(str), &expected); err != nil {
+		t.Fatal(err)
+	}
+	d := xml.NewTokenDecoder(NewReader(strings.NewReader(str)).TokenReader())
+	if err := d.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != expected || got.V != "x<y" {
+		t.Fatalf("Unexpected decoding: %+v. Expected %+v", got, expected)
+	}
+}
@@ -0,0 +1,366 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Action tells the Transformer what to do with an element
+// passed to a TransformFunc.
+type Action int
+
+const (
+	// Copy writes the element as it was in the input.
+	Copy Action = iota
+	// Rewrite serializes the element, which might have been modified.
+	Rewrite
+	// Drop removes the element.
+	// If the element is a StartElement its children are removed too.
+	Drop
+)
+
+// TransformFunc is the function called by the Transformer
+// when an element matches the path it has been registered with.
+type TransformFunc func(c *TransformContext) Action
+
+// TransformContext holds the element being transformed.
+//
+// It is only valid inside the TransformFunc.
+type TransformContext struct {
+	t        *Transformer
+	e        Element
+	before   []Element
+	after    []Element
+	children []Element
+}
+
+// Element returns the element matching the path.
+//
+// The element can be modified. To write the modifications
+// the TransformFunc must return Rewrite.
+func (c *TransformContext) Element() Element {
+	return c.e
+}
+
+// Path returns the path of the current element, like `feed/entry`.
+func (c *TransformContext) Path() string {
+	return string(c.t.path)
+}
+
+// InsertBefore writes es before the element.
+func (c *TransformContext) InsertBefore(es ...Element) {
+	c.before = append(c.before, es...)
+}
+
+// InsertAfter writes es after the element.
+//
+// For a StartElement the elements are written after its EndElement.
+func (c *TransformContext) InsertAfter(es ...Element) {
+	c.after = append(c.after, es...)
+}
+
+// Append writes es as the last children of a StartElement.
+func (c *TransformContext) Append(es ...Element) {
+	c.children = append(c.children, es...)
+}
+
+func (c *TransformContext) reset(e Element) {
+	c.e = e
+	c.before = c.before[:0]
+	c.after = c.after[:0]
+	c.children = c.children[:0]
+}
+
+type transformHandler struct {
+	path []string
+	text bool
+	fn   TransformFunc
+}
+
+// transformFrame holds the elements pending to be written
+// when the EndElement of an open element is found.
+type transformFrame struct {
+	children []Element
+	after    []Element
+}
+
+// Transformer pipes a Reader into a Writer allowing to modify
+// the elements on the way.
+//
+// The elements not matching any handler, as well as comments,
+// whitespaces and any other construct between them, are copied
+// from the input without being serialized again.
+type Transformer struct {
+	r        *Reader
+	w        *Writer
+	handlers []transformHandler
+	path     []byte
+	ends     []int
+	frames   []transformFrame
+	ctx      TransformContext
+}
+
+// NewTransformer creates a Transformer reading from r and writing to w.
+func NewTransformer(r *Reader, w *Writer) *Transformer {
+	t := &Transformer{
+		r: r,
+		w: w,
+	}
+	t.ctx.t = t
+	return t
+}
+
+// Handle registers fn to be called on every StartElement matching path.
+//
+// A path is a list of element names separated by `/` starting
+// from the root element, like `feed/entry/title`. The `*` name
+// matches any element.
+func (t *Transformer) Handle(path string, fn TransformFunc) {
+	t.handlers = append(t.handlers, transformHandler{
+		path: splitPath(path),
+		fn:   fn,
+	})
+}
+
+// HandleText registers fn to be called on every TextElement
+// whose parent element matches path.
+func (t *Transformer) HandleText(path string, fn TransformFunc) {
+	t.handlers = append(t.handlers, transformHandler{
+		path: splitPath(path),
+		text: true,
+		fn:   fn,
+	})
+}
+
+// Run transforms the input until EOF.
+func (t *Transformer) Run() (err error) {
+	r := t.r
+	r.r.record = true
+	defer func() {
+		r.r.record = r.keepRaw
+	}()
+
+	for err == nil && r.Next() {
+		raw := r.r.raw
+		prefix, tok := raw[:r.tok], raw[r.tok:]
+
+		switch e := r.Element().(type) {
+		case *StartElement:
+			t.push(e.NameBytes())
+			err = t.start(e, prefix, tok)
+		case *EndElement:
+			err = t.end(prefix, tok)
+		case *TextElement:
+			err = t.text(e, prefix, tok)
+		default:
+			err = t.w.writeBytes(raw)
+		}
+	}
+	if err == nil {
+		if err = r.Error(); err == io.EOF {
+			err = t.w.writeBytes(r.r.raw)
+		}
+	}
+
+	return err
+}
+
+func (t *Transformer) start(e *StartElement, prefix, tok []byte) (err error) {
+	fn := t.match(false)
+	if fn == nil {
+		if err = t.w.writeBytes(prefix); err == nil {
+			err = t.w.writeBytes(tok)
+		}
+		if e.HasEnd() {
+			t.pop()
+		} else {
+			t.frames = append(t.frames, transformFrame{})
+		}
+		return err
+	}
+
+	c := &t.ctx
+	c.reset(e)
+	action := fn(c)
+
+	if err = t.w.writeBytes(prefix); err == nil {
+		err = t.writeAll(c.before)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case Drop:
+		if !e.HasEnd() {
+			err = t.skip()
+		}
+		t.pop()
+		if err == nil {
+			err = t.writeAll(c.after)
+		}
+		return err
+	case Rewrite:
+		if e.HasEnd() && len(c.children) > 0 {
+			e.hasEnd = false
+			err = t.w.Write(e)
+			e.hasEnd = true
+		} else {
+			err = t.w.Write(e)
+		}
+	default:
+		if e.HasEnd() && len(c.children) > 0 {
+			e.hasEnd = false
+			err = t.w.Write(e)
+			e.hasEnd = true
+		} else {
+			err = t.w.writeBytes(tok)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if e.HasEnd() {
+		if len(c.children) > 0 {
+			if err = t.writeAll(c.children); err == nil {
+				err = t.w.Write(NewEnd(e.Name()))
+			}
+		}
+		t.pop()
+		if err == nil {
+			err = t.writeAll(c.after)
+		}
+		return err
+	}
+
+	t.frames = append(t.frames, transformFrame{
+		children: append([]Element(nil), c.children...),
+		after:    append([]Element(nil), c.after...),
+	})
+
+	return nil
+}
+
+func (t *Transformer) end(prefix, tok []byte) (err error) {
+	var frame transformFrame
+	if n := len(t.frames); n > 0 {
+		frame = t.frames[n-1]
+		t.frames = t.frames[:n-1]
+	}
+	t.pop()
+
+	if err = t.w.writeBytes(prefix); err == nil {
+		if err = t.writeAll(frame.children); err == nil {
+			if err = t.w.writeBytes(tok); err == nil {
+				err = t.writeAll(frame.after)
+			}
+		}
+	}
+	return err
+}
+
+func (t *Transformer) text(e *TextElement, prefix, tok []byte) (err error) {
+	fn := t.match(true)
+	if fn == nil {
+		if err = t.w.writeBytes(prefix); err == nil {
+			err = t.w.writeBytes(tok)
+		}
+		return err
+	}
+
+	c := &t.ctx
+	c.reset(e)
+	action := fn(c)
+
+	if err = t.w.writeBytes(prefix); err == nil {
+		err = t.writeAll(c.before)
+	}
+	if err == nil {
+		switch action {
+		case Copy:
+			err = t.w.writeBytes(tok)
+		case Rewrite:
+			err = t.w.Write(e)
+		}
+	}
+	if err == nil {
+		err = t.writeAll(c.after)
+	}
+	return err
+}
+
+// skip discards the children of the current element.
+func (t *Transformer) skip() error {
+	r := t.r
+	for depth := 1; depth > 0 && r.Next(); {
+		switch e := r.Element().(type) {
+		case *StartElement:
+			if !e.HasEnd() {
+				depth++
+			}
+		case *EndElement:
+			depth--
+		}
+	}
+	return r.Error()
+}
+
+func (t *Transformer) writeAll(es []Element) (err error) {
+	for _, e := range es {
+		if err = t.w.Write(e); err != nil {
+			break
+		}
+	}
+	return
+}
+
+func (t *Transformer) push(name []byte) {
+	t.ends = append(t.ends, len(t.path))
+	if len(t.path) > 0 {
+		t.path = append(t.path, '/')
+	}
+	t.path = append(t.path, name...)
+}
+
+func (t *Transformer) pop() {
+	if n := len(t.ends); n > 0 {
+		t.path = t.path[:t.ends[n-1]]
+		t.ends = t.ends[:n-1]
+	}
+}
+
+// match returns the first handler matching the current path.
+func (t *Transformer) match(text bool) TransformFunc {
+	for i := range t.handlers {
+		h := &t.handlers[i]
+		if h.text == text && matchPath(h.path, t.path) {
+			return h.fn
+		}
+	}
+	return nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// matchPath reports whether the `/` separated path matches the pattern.
+func matchPath(pattern []string, path []byte) bool {
+	for _, name := range pattern {
+		if len(path) == 0 {
+			return false
+		}
+		seg := path
+		if i := bytes.IndexByte(path, '/'); i >= 0 {
+			seg, path = path[:i], path[i+1:]
+		} else {
+			path = path[:0]
+		}
+		if name != "*" && name != b2s(seg) {
+			return false
+		}
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,70 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformer(t *testing.T) {
+	const input = `<?xml version="1.0"?>
+<!-- books -->
+<bookstore>
+	<book   category='COOKING' id="1">
+	  <title lang="en">Everyday Italian</title>
+	  <price>30.00</price>
+	</book>
+	<book category="WEB" id="2"><title>Learning XML</title><price>39.95</price></book>
+	<book category="WEB" id="3"/>
+</bookstore>
+`
+	const expected = `<?xml version="1.0"?>
+<!-- books -->
+<bookstore>
+	<book category="cooking" id="1">
+	  <title lang="en">Everyday Italian</title>
+	  
+	<stock>yes</stock></book>
+	<book category="web" id="2"><title>Learning XML</title><stock>yes</stock></book>
+	<book category="web" id="3"><stock>yes</stock></book><book id="4"/>
+</bookstore>
+`
+
+	var sb strings.Builder
+	tr := NewTransformer(NewReader(strings.NewReader(input)), NewWriter(&sb))
+	tr.Handle("bookstore/book", func(c *TransformContext) Action {
+		e := c.Element().(*StartElement)
+		kv := e.Attrs().Get("category")
+		kv.v = []byte(strings.ToLower(kv.Value()))
+		c.Append(NewStart("stock", false, nil), NewText("yes"), NewEnd("stock"))
+		if e.Attrs().Get("id").Value() == "3" {
+			c.InsertAfter(NewStart("book", true, NewAttrs("id", "4")))
+		}
+		return Rewrite
+	})
+	tr.Handle("bookstore/*/price", func(c *TransformContext) Action {
+		return Drop
+	})
+
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != expected {
+		t.Fatalf("Unexpected output:\n%s\nExpected:\n%s", sb.String(), expected)
+	}
+}
+
+func TestTransformerCopy(t *testing.T) {
+	const input = "  <a  x = 'y' ><!-- c --><b/>text  </ a >\n\n"
+
+	var sb strings.Builder
+	tr := NewTransformer(NewReader(strings.NewReader(input)), NewWriter(&sb))
+	tr.HandleText("a", func(c *TransformContext) Action {
+		return Copy
+	})
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != input {
+		t.Fatalf("Unexpected output: %q. Expected %q", sb.String(), input)
+	}
+}
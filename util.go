@@ -1,20 +1,254 @@
 package xml
 
 import (
-	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 )
 
-func skipWS(r *bufio.Reader) (c byte, err error) {
+func b2s(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// trimWS returns b without leading and trailing whitespaces.
+func trimWS(b []byte) []byte {
+	for len(b) > 0 && b[0] <= 32 {
+		b = b[1:]
+	}
+	for len(b) > 0 && b[len(b)-1] <= 32 {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// normalizeNewlines replaces the CRLF and CR line endings of b by LF in place.
+func normalizeNewlines(b []byte) []byte {
+	i := bytes.IndexByte(b, '\r')
+	if i < 0 {
+		return b
+	}
+
+	n := i
+	for ; i < len(b); i++ {
+		c := b[i]
+		if c == '\r' {
+			c = '\n'
+			if i+1 < len(b) && b[i+1] == '\n' {
+				i++
+			}
+		}
+		b[n] = c
+		n++
+	}
+	return b[:n]
+}
+
+// normalizeAttr normalizes the attribute value b in place replacing
+// every line ending, tab and newline by a space.
+func normalizeAttr(b []byte) []byte {
+	b = normalizeNewlines(b)
+	for i, c := range b {
+		if c == '\n' || c == '\t' {
+			b[i] = ' '
+		}
+	}
+	return b
+}
+
+// parseBool parses an XML Schema boolean: 1, 0, true or false.
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	}
+	return false, &strconv.NumError{
+		Func: "ParseBool",
+		Num:  strings.Clone(s),
+		Err:  strconv.ErrSyntax,
+	}
+}
+
+// parseTime parses s using layout.
+//
+// s can be an unsafe string, so on error the value is parsed again
+// from a copy to avoid the error referencing the reader's memory.
+func parseTime(layout, s string) (time.Time, error) {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		t, err = time.Parse(layout, strings.Clone(s))
+	}
+	return t, err
+}
+
+// Unescape appends to dst the src bytes replacing the predefined
+// XML entities and the character references by the characters they represent.
+//
+// The Reader doesn't unescape the text nor the attribute values,
+// so Unescape can be used to get the actual characters.
+// Unknown entities are left as they are.
+func Unescape(dst, src []byte) []byte {
 	for {
-		c, err = r.ReadByte()
-		if err != nil || c > 32 {
+		i := bytes.IndexByte(src, '&')
+		if i < 0 {
 			break
 		}
+		dst = append(dst, src[:i]...)
+		src = src[i:]
+
+		j := bytes.IndexByte(src, ';')
+		if j < 0 {
+			break
+		}
+
+		if r, ok := entityRune(src[1:j]); ok {
+			dst = utf8.AppendRune(dst, r)
+		} else {
+			dst = append(dst, src[:j+1]...)
+		}
+		src = src[j+1:]
 	}
-	return
+	return append(dst, src...)
 }
 
-func b2s(b []byte) string {
-	return *(*string)(unsafe.Pointer(&b))
+// entityRune returns the character represented by the entity name.
+func entityRune(name []byte) (rune, bool) {
+	switch b2s(name) {
+	case "lt":
+		return '<', true
+	case "gt":
+		return '>', true
+	case "amp":
+		return '&', true
+	case "apos":
+		return '\'', true
+	case "quot":
+		return '"', true
+	}
+	if len(name) < 2 || name[0] != '#' {
+		return 0, false
+	}
+
+	var (
+		n   uint64
+		err error
+	)
+	if name[1] == 'x' {
+		n, err = strconv.ParseUint(b2s(name[2:]), 16, 32)
+	} else {
+		n, err = strconv.ParseUint(b2s(name[1:]), 10, 32)
+	}
+	if err != nil || !utf8.ValidRune(rune(n)) {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+// escapeText appends to dst the text src escaping the XML special characters.
+func escapeText(dst, src []byte) []byte {
+	return escape(dst, src, false)
+}
+
+// escapeAttr appends to dst the attribute value src escaping
+// the XML special characters and the quotes.
+func escapeAttr(dst, src []byte) []byte {
+	return escape(dst, src, true)
+}
+
+// isName reports whether s matches the Name production of the XML specification.
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return false
+			}
+		}
+		if !isNameStartChar(c) && (i == 0 || !isNameChar(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStartChar(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		return true
+	case c < 0xC0:
+		return false
+	}
+	return c <= 0xD6 || (c >= 0xD8 && c <= 0xF6) || (c >= 0xF8 && c <= 0x2FF) ||
+		(c >= 0x370 && c <= 0x37D) || (c >= 0x37F && c <= 0x1FFF) ||
+		(c >= 0x200C && c <= 0x200D) || (c >= 0x2070 && c <= 0x218F) ||
+		(c >= 0x2C00 && c <= 0x2FEF) || (c >= 0x3001 && c <= 0xD7FF) ||
+		(c >= 0xF900 && c <= 0xFDCF) || (c >= 0xFDF0 && c <= 0xFFFD) ||
+		(c >= 0x10000 && c <= 0xEFFFF)
+}
+
+func isNameChar(c rune) bool {
+	return (c >= '0' && c <= '9') || c == '-' || c == '.' || c == 0xB7 ||
+		(c >= 0x300 && c <= 0x36F) || (c >= 0x203F && c <= 0x2040)
+}
+
+// indexFold returns the index of the first occurrence of seq in b
+// ignoring the ASCII case, or -1.
+func indexFold(b []byte, seq string) int {
+	sb := []byte(seq)
+	for i := 0; len(b)-i >= len(seq); i++ {
+		j := bytes.IndexByte(b[i:], seq[0])
+		if j < 0 || len(b)-i-j < len(seq) {
+			return -1
+		}
+		i += j
+		if bytes.EqualFold(b[i:i+len(seq)], sb) {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendQuoted appends to dst the attribute value v escaping the double quotes,
+// which values read between single quotes can contain.
+func appendQuoted(dst, v []byte) []byte {
+	for {
+		i := bytes.IndexByte(v, '"')
+		if i < 0 {
+			return append(dst, v...)
+		}
+		dst = append(append(dst, v[:i]...), "&quot;"...)
+		v = v[i+1:]
+	}
+}
+
+func escape(dst, src []byte, attr bool) []byte {
+	last := 0
+	for i, c := range src {
+		var esc string
+		switch c {
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '&':
+			esc = "&amp;"
+		case '"':
+			if !attr {
+				continue
+			}
+			esc = "&quot;"
+		default:
+			continue
+		}
+		dst = append(dst, src[last:i]...)
+		dst = append(dst, esc...)
+		last = i + 1
+	}
+	return append(dst, src[last:]...)
 }
@@ -1,36 +1,95 @@
 package xml
 
-import "io"
+import (
+	"io"
+	"sync"
+)
+
+// xmlAppender is implemented by the elements that can append their XML
+// to a buffer, avoiding the allocation of String.
+type xmlAppender interface {
+	AppendXML(dst []byte) []byte
+}
+
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// writeTo writes the XML of e to w using a pooled buffer.
+func writeTo(w io.Writer, e xmlAppender) (int64, error) {
+	b := bufPool.Get().(*[]byte)
+	*b = e.AppendXML((*b)[:0])
+	n, err := w.Write(*b)
+	bufPool.Put(b)
+	return int64(n), err
+}
 
 // Writer is used to write the XML elements.
 type Writer struct {
 	w      io.Writer
-	indent string
+	depth  int    // indentation level of WriteIndent.
+	out    []byte // holds the element being written.
+	stream []byte // name of the root opened with OpenStream.
+
+	// used by WriteToken
+	start StartElement
+	buf   []byte
+	ns    []nsBinding
+	marks []int
 }
 
 // NewWriter creates a new XML writer.
 func NewWriter(w io.Writer) *Writer {
-	return &Writer{w, ""}
+	return &Writer{
+		w: w,
+	}
 }
 
 // Write writes the parsed element.
 func (w *Writer) Write(e Element) error {
-	return writeString(w.w, e.String())
+	w.out = w.appendElement(w.out[:0], e)
+	return w.writeBytes(w.out)
+}
+
+// appendElement appends the XML of e to dst.
+func (w *Writer) appendElement(dst []byte, e Element) []byte {
+	if a, ok := e.(xmlAppender); ok {
+		return a.AppendXML(dst)
+	}
+	return append(dst, e.String()...)
+}
+
+// WriteRaw writes the raw bytes of e (see Reader.SetKeepRaw).
+//
+// If e doesn't hold any raw bytes it is serialized like in Write.
+// That is the case of TextElements, whose raw bytes are returned by Reader.Raw.
+func (w *Writer) WriteRaw(e Element) error {
+	if raw := e.Raw(); len(raw) > 0 {
+		return w.writeBytes(raw)
+	}
+	return w.Write(e)
 }
 
 // WriteIndent writes the parsed element indentating the elements.
 func (w *Writer) WriteIndent(e Element) error {
-	if _, ok := e.(*EndElement); ok {
-		w.indent = w.indent[:len(w.indent)-2]
+	if _, ok := e.(*EndElement); ok && w.depth > 0 {
+		w.depth--
 	}
 
-	err := writeString(w.w, w.indent, e.String(), "\n")
+	w.out = w.out[:0]
+	for i := 0; i < w.depth; i++ {
+		w.out = append(w.out, ' ', ' ')
+	}
+	w.out = w.appendElement(w.out, e)
+	w.out = append(w.out, '\n')
 
 	if e, ok := e.(*StartElement); ok && !e.hasEnd {
-		w.indent += "  "
+		w.depth++
 	}
 
-	return err
+	return w.writeBytes(w.out)
 }
 
 func writeString(w io.Writer, strs ...string) (err error) {
@@ -43,3 +102,10 @@ func writeString(w io.Writer, strs ...string) (err error) {
 
 	return
 }
+
+func (w *Writer) writeBytes(b []byte) (err error) {
+	if len(b) > 0 {
+		_, err = w.w.Write(b)
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAppendXML(t *testing.T) {
+	s := NewStart("el", true, nil)
+	s.Attrs().Add("a", "x")
+	s.Attrs().Add("b", "&amp;")
+
+	elements := []struct {
+		e        Element
+		expected string
+	}{
+		{s, `<el a="x" b="&amp;"/>`},
+		{NewEnd("el"), `</el>`},
+		{NewText("a &lt; b"), `a &lt; b`},
+	}
+	for _, tc := range elements {
+		if got := string(tc.e.(xmlAppender).AppendXML([]byte("~"))); got != "~"+tc.expected {
+			t.Fatalf("got %s. Expected %s", got, tc.expected)
+		}
+		if tc.e.String() != tc.expected {
+			t.Fatalf("got %s. Expected %s", tc.e.String(), tc.expected)
+		}
+
+		var b bytes.Buffer
+		n, err := tc.e.(io.WriterTo).WriteTo(&b)
+		if err != nil || n != int64(len(tc.expected)) || b.String() != tc.expected {
+			t.Fatalf("WriteTo wrote %d bytes %s: %v", n, b.String(), err)
+		}
+	}
+
+	if got := string(s.Attrs().AppendXML(nil)); got != ` a="x" b="&amp;"` {
+		t.Fatalf("unexpected attrs %s", got)
+	}
+}
+
+func TestWriterIndent(t *testing.T) {
+	var b strings.Builder
+	w := NewWriter(&b)
+
+	r := NewReader(strings.NewReader(`<a><b x="1"><c/></b></a>`))
+	for r.Next() {
+		if err := w.WriteIndent(r.Element()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const expected = "<a>\n  <b x=\"1\">\n    <c/>\n  </b>\n</a>\n"
+	if b.String() != expected {
+		t.Fatalf("\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
+
+func TestWriterAllocs(t *testing.T) {
+	s := NewStart("row", false, nil)
+	s.Attrs().Add("r", "1")
+	s.Attrs().Add("spans", "1:3")
+	text := NewText("cell")
+	end := NewEnd("row")
+
+	w := NewWriter(io.Discard)
+	allocs := testing.AllocsPerRun(100, func() {
+		w.Write(s)
+		w.Write(text)
+		w.Write(end)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations. Got %v", allocs)
+	}
+}